@@ -0,0 +1,34 @@
+package fasthttp_router
+
+import (
+	"fmt"
+	"strings"
+)
+
+// ConflictError is the error of registering a route that conflicts with an
+// existing one.
+type ConflictError struct {
+	// Method is the method of both routes.
+	Method string
+
+	// Existing is the pattern of the route already registered.
+	Existing string
+
+	// Pattern is the pattern of the route being registered.
+	Pattern string
+}
+
+func (err *ConflictError) Error() string {
+	return fmt.Sprintf("conflict adding '%s' to %s: it conflicts with '%s'", err.Pattern, err.Method, err.Existing)
+}
+
+// Errors is the list of errors collected while registering routes.
+type Errors []error
+
+func (errs Errors) Error() string {
+	messages := make([]string, len(errs))
+	for i, err := range errs {
+		messages[i] = err.Error()
+	}
+	return strings.Join(messages, "; ")
+}
@@ -0,0 +1,127 @@
+package fasthttp_router
+
+import (
+	"github.com/valyala/fasthttp"
+	"bytes"
+	"fmt"
+	"strings"
+)
+
+// hostRoute holds the routes registered for the hosts matching a pattern.
+type hostRoute struct {
+	pattern string
+	labels  [][]byte
+	names   []string
+	routes  *Router
+}
+
+// Host returns the Routable for the requests to the hosts matching
+// `pattern`, a dot separated list of labels where `:name` labels capture
+// the label of the host, as in `:tenant.example.com`. Captured labels are
+// stored as the first parameters of the request.
+//
+// Host routes are tried before the routes of the router itself, in the
+// order their patterns were added. Requests to other hosts, or whose path
+// has no host route, fall back to the router itself. The redirects, the
+// OPTIONS and the method not allowed responses consider the routes of the
+// matching hosts too. The options of the router
+// apply to its host routes.
+func (router *Router) Host(pattern string) Routable {
+	for _, host := range router.hosts {
+		if strings.EqualFold(host.pattern, pattern) {
+			return host.routes
+		}
+	}
+	host := &hostRoute{
+		pattern: pattern,
+		routes:  New(),
+	}
+	host.routes.paramTypes = router.paramTypes
+	host.routes.parent = router
+	for _, label := range strings.Split(pattern, ".") {
+		if label == "" || label == ":" {
+			// The routes added to the Routable of an invalid pattern are
+			// checked, but never served.
+			router.fail(fmt.Errorf("invalid host pattern '%s'", pattern))
+			return host.routes
+		}
+		if label[0] == ':' {
+			host.names = append(host.names, label[1:])
+		}
+		host.labels = append(host.labels, []byte(label))
+	}
+	router.hosts = append(router.hosts, host)
+	return host.routes
+}
+
+// Matches reports whether `host` matches the pattern, appending the
+// captured labels to `values`.
+func (host *hostRoute) Matches(name []byte, values [][]byte) (bool, [][]byte) {
+	for i, label := range host.labels {
+		end := bytes.IndexByte(name, '.')
+		if i == len(host.labels)-1 {
+			if end >= 0 {
+				return false, nil
+			}
+			end = len(name)
+		} else if end < 0 {
+			return false, nil
+		}
+		if label[0] == ':' {
+			if end == 0 {
+				return false, nil
+			}
+			values = append(values, name[:end])
+		} else if !equalFold(label, name[:end]) {
+			return false, nil
+		}
+		if end < len(name) {
+			name = name[end+1:]
+		}
+	}
+	return true, values
+}
+
+// hostRoutes returns the routers of the hosts matching the request.
+func (router *Router) hostRoutes(ctx *fasthttp.RequestCtx) []*Router {
+	var routes []*Router
+	name := hostName(ctx.Host())
+	for _, host := range router.hosts {
+		if found, _ := host.Matches(name, nil); found {
+			routes = append(routes, host.routes)
+		}
+	}
+	return routes
+}
+
+// hostName returns `host` without its port.
+func hostName(host []byte) []byte {
+	if i := bytes.LastIndexByte(host, ':'); i >= 0 && bytes.IndexByte(host[i:], ']') < 0 {
+		return host[:i]
+	}
+	return host
+}
+
+// dispatchHost calls the handler of the first host route matching the
+// request, reporting whether one was found.
+func (router *Router) dispatchHost(ctx *fasthttp.RequestCtx, method string, path []byte, state *requestState) bool {
+	name := hostName(ctx.Host())
+	for _, host := range router.hosts {
+		found, values := host.Matches(name, state.values[:0])
+		if !found {
+			continue
+		}
+		state.values = values
+		if router.dispatch(ctx, host.routes.tree(method), path, state, host.names) {
+			return true
+		}
+		if method == "HEAD" && router.HandleHEAD {
+			ctx.Response.SkipBody = true
+			if router.dispatch(ctx, host.routes.trees[methodGET], path, state, host.names) {
+				return true
+			}
+			ctx.Response.SkipBody = false
+		}
+	}
+	return false
+}
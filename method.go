@@ -0,0 +1,52 @@
+package fasthttp_router
+
+// Indexes of the standard methods in Router.trees.
+const (
+	methodGET = iota
+	methodHEAD
+	methodPOST
+	methodPUT
+	methodPATCH
+	methodDELETE
+	methodOPTIONS
+	methodCONNECT
+	methodTRACE
+	methodCount
+)
+
+var methodNames = [methodCount]string{
+	methodGET:     "GET",
+	methodHEAD:    "HEAD",
+	methodPOST:    "POST",
+	methodPUT:     "PUT",
+	methodPATCH:   "PATCH",
+	methodDELETE:  "DELETE",
+	methodOPTIONS: "OPTIONS",
+	methodCONNECT: "CONNECT",
+	methodTRACE:   "TRACE",
+}
+
+// methodIndex returns the index of a standard method, or -1 for custom ones.
+func methodIndex(method []byte) int {
+	switch string(method) {
+	case "GET":
+		return methodGET
+	case "HEAD":
+		return methodHEAD
+	case "POST":
+		return methodPOST
+	case "PUT":
+		return methodPUT
+	case "PATCH":
+		return methodPATCH
+	case "DELETE":
+		return methodDELETE
+	case "OPTIONS":
+		return methodOPTIONS
+	case "CONNECT":
+		return methodCONNECT
+	case "TRACE":
+		return methodTRACE
+	}
+	return -1
+}
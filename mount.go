@@ -0,0 +1,110 @@
+package fasthttp_router
+
+import (
+	"github.com/valyala/fasthttp"
+	"bytes"
+	"fmt"
+	"strings"
+)
+
+// MountPrefixKey is the user value key the prefix a request was mounted
+// under is stored at, for the handlers of mounted routers.
+const MountPrefixKey = "fasthttp_router.mount_prefix"
+
+type mount struct {
+	path    string
+	prefix  []byte
+	value   interface{}
+	handler fasthttp.RequestHandler
+	routes  *Router
+}
+
+// Mount serves the requests under `prefix` with `sub`, an independently
+// built router, which keeps its own NotFound, method not allowed and other
+// options. See MountHandler.
+func (router *Router) Mount(prefix string, sub *Router) {
+	router.mount(prefix, sub.Handler, sub)
+}
+
+// MountHandler serves the requests under `prefix`, a static path, with
+// `handler`, whatever their method. The routes of the router have priority
+// over the mounted handlers, and the longest prefix wins among them.
+//
+// The handler sees the request path without the prefix, `/` for the prefix
+// itself, and finds the prefix as the MountPrefixKey user value. The prefix
+// is compared with the path the routes are matched against, so cleaned and
+// decoded unless UseRawPath is set. The path is restored once the handler
+// returns.
+func (router *Router) MountHandler(prefix string, handler fasthttp.RequestHandler) {
+	router.mount(prefix, handler, nil)
+}
+
+// mount adds `handler` under `prefix`, keeping `routes`, the mounted router
+// if any, for Walk.
+func (router *Router) mount(prefix string, handler fasthttp.RequestHandler, routes *Router) {
+	if prefix == "" || prefix[0] != '/' || strings.ContainsAny(prefix, ":*") {
+		router.fail(fmt.Errorf("invalid mount prefix '%s'", prefix))
+		return
+	}
+	prefix = strings.TrimRight(prefix, "/")
+	for _, m := range router.mounts {
+		if m.path == prefix {
+			router.fail(fmt.Errorf("conflict mounting '%s'", prefix))
+			return
+		}
+	}
+	router.mounts = append(router.mounts, &mount{
+		path:    prefix,
+		prefix:  []byte(prefix),
+		value:   prefix,
+		handler: handler,
+		routes:  routes,
+	})
+}
+
+// dispatchMount calls the handler mounted under the longest prefix of the
+// requested path, reporting whether there was one.
+func (router *Router) dispatchMount(ctx *fasthttp.RequestCtx, state *requestState) bool {
+	uri := ctx.Request.URI()
+	path := router.requestPath(uri)
+	var found *mount
+	for _, m := range router.mounts {
+		if bytes.HasPrefix(path, m.prefix) && (len(path) == len(m.prefix) || path[len(m.prefix)] == '/') {
+			if found == nil || len(m.prefix) > len(found.prefix) {
+				found = m
+			}
+		}
+	}
+	if found == nil {
+		return false
+	}
+
+	state.buf = append(state.buf[:0], uri.PathOriginal()...)
+	end := len(state.buf)
+	if len(path) == len(found.prefix) {
+		uri.SetPath("/")
+	} else if router.UseRawPath {
+		state.buf = append(state.buf, path[len(found.prefix):]...)
+		uri.SetPathBytes(state.buf[end:])
+	} else {
+		state.buf = appendEscapedPercent(state.buf, path[len(found.prefix):])
+		uri.SetPathBytes(state.buf[end:])
+	}
+	ctx.SetUserValue(MountPrefixKey, found.value)
+	found.handler(ctx)
+	uri.SetPathBytes(state.buf[:end])
+	return true
+}
+
+// appendEscapedPercent appends `path`, already decoded, to `dst` escaping
+// its `%` so it is not decoded twice.
+func appendEscapedPercent(dst, path []byte) []byte {
+	for _, c := range path {
+		if c == '%' {
+			dst = append(dst, "%25"...)
+			continue
+		}
+		dst = append(dst, c)
+	}
+	return dst
+}
@@ -0,0 +1,119 @@
+package fasthttp_router
+
+import (
+	"bytes"
+	"fmt"
+	"net/url"
+	"strings"
+)
+
+// Route is a registered route, returned by the registration methods to
+// name it.
+type Route struct {
+	router  *Router
+	pattern string
+}
+
+// Name names the route, so URL can build its paths. It panics when the
+// name is already taken, unless CollectErrors is on.
+func (route *Route) Name(name string) *Route {
+	router := route.router
+	if _, ok := router.names[name]; ok {
+		router.fail(fmt.Errorf("duplicate route name '%s'", name))
+		return route
+	}
+	if router.names == nil {
+		router.names = make(map[string]string)
+	}
+	router.names[name] = route.pattern
+	return route
+}
+
+// URL returns the path of the route named `name`, with the parameters set
+// to `params`, given as name and value pairs. Values are escaped, keeping
+// the slashes of catch-all values. Optional segments are left out when
+// none of their parameters are given.
+func (router *Router) URL(name string, params ...string) (string, error) {
+	pattern, ok := router.owner().names[name]
+	if !ok {
+		return "", fmt.Errorf("unknown route name '%s'", name)
+	}
+	if len(params)%2 != 0 {
+		return "", fmt.Errorf("missing value of parameter '%s' for route '%s'", params[len(params)-1], name)
+	}
+	values := make(map[string]string, len(params)/2)
+	for i := 0; i < len(params); i += 2 {
+		values[params[i]] = params[i+1]
+	}
+
+	used := make(map[string]bool, len(values))
+	segments := make([]string, 0)
+	for _, segment := range strings.Split(pattern[1:], "/") {
+		segment, optional := optionalSegment(segment)
+		parts, names, err := splitSegment(pattern, segment)
+		if err != nil {
+			return "", err
+		}
+		given := 0
+		for _, param := range names {
+			if _, ok := values[param]; ok {
+				given++
+				used[param] = true
+			}
+		}
+		if optional && given == 0 && len(names) > 0 {
+			continue
+		}
+		for i := 1; i < len(parts); i += 2 {
+			param := parts[i]
+			if param[0] == '*' {
+				value, ok := values[param[1:]]
+				if !ok {
+					return "", fmt.Errorf("missing parameter '%s' for route '%s'", param[1:], name)
+				}
+				escaped := strings.Split(value, "/")
+				for j := range escaped {
+					escaped[j] = url.PathEscape(escaped[j])
+				}
+				parts[i] = strings.Join(escaped, "/")
+				continue
+			}
+			if values[param] == "" {
+				return "", fmt.Errorf("missing parameter '%s' for route '%s'", param, name)
+			}
+			parts[i] = url.PathEscape(values[param])
+		}
+		segments = append(segments, strings.Join(parts, ""))
+	}
+	for i := 0; i < len(params); i += 2 {
+		if !used[params[i]] {
+			return "", fmt.Errorf("unexpected parameter '%s' for route '%s'", params[i], name)
+		}
+	}
+	return "/" + strings.Join(segments, "/"), nil
+}
+
+// splitSegment splits a segment of `pattern` into its parts, alternating
+// literals and parameter names, catch-alls keeping their `*`, and returns
+// the names of its parameters.
+func splitSegment(pattern, segment string) ([]string, []string, error) {
+	if len(segment) > 0 && segment[0] == '*' {
+		return []string{"", segment, ""}, []string{segment[1:]}, nil
+	}
+	parts := make([]string, 0, 1)
+	names := make([]string, 0)
+	token := []byte(segment)
+	for {
+		j := bytes.IndexByte(token, ':')
+		if j < 0 {
+			return append(parts, string(token)), names, nil
+		}
+		name, _, rest, err := parseParam(pattern, token[j+1:])
+		if err != nil {
+			return nil, nil, err
+		}
+		parts = append(parts, string(token[:j]), name)
+		names = append(names, name)
+		token = rest
+	}
+}
@@ -4,106 +4,506 @@ import (
 	"github.com/valyala/fasthttp"
 	"bytes"
 	"fmt"
+	"regexp"
 )
 
+// node is a node of a prefix compressed radix tree. A node matches the
+// static bytes of its prefix and then, in this order, one of its static
+// children (selected by the first byte of their prefixes), one of its
+// constrained wildcards (a `:param{regexp}` or `:param<type>` value accepted
+// by its matcher, in registration order), its wildcard (a `:param` value) or
+// its catch-all (the rest of the path).
+//
+// A wildcard value spans its whole segment unless the wildcard is
+// `inSegment`, followed by literals of its segment as in `:name.json`. It
+// may then also end right before any of its static children, the shortest
+// values being tried first.
 type node struct {
-	wildcard *node
-	children map[string]*node
-	handler  fasthttp.RequestHandler
-	names    []string
+	prefix      []byte
+	indices     []byte
+	children    []*node
+	constrained []*node
+	wildcard    *node
+	catchAll    *node
+	constraint  string
+	match       func([]byte) bool
+	inSegment   bool
+	handler     fasthttp.RequestHandler
+	handlerName string
+	names       []string
+	route       string
+	path        string
 }
 
 func newNode() *node {
-	return &node{
-		children: make(map[string]*node),
-	}
+	return &node{}
 }
 
-func (n *node) Add(path string, handler fasthttp.RequestHandler, names []string) {
+// Add registers `handler`, named `handlerName` before being wrapped by
+// middlewares, for `path`, given without its leading slash, as the route
+// `route`. It returns a function undoing the registration, for when the
+// other variants of an optional route fail.
+func (n *node) Add(path string, handler fasthttp.RequestHandler, handlerName, route string, types map[string]func([]byte) bool) (func(), error) {
 	pathBytes := bytes.Split([]byte(path), []byte{'/'})
 	lpath := len(pathBytes)
 	parent := n
+	static := make([]byte, 0, len(path))
+	var names []string
 	for i := 0; i < lpath; i++ {
 		token := pathBytes[i]
-		if len(token) > 0 {
-			if token[0] == ':' {
-				name := string(token[1:])
-				node := parent.wildcard
-				nodeCreated := false
-				if node == nil {
-					node = newNode()
-					parent.wildcard = node
-					nodeCreated = true
+		if i > 0 {
+			static = append(static, '/')
+		}
+		if len(token) == 0 {
+			if i+1 < lpath {
+				return nil, fmt.Errorf("empty token in '%s'", route)
+			}
+			// A trailing slash is kept in the static bytes, so `/path/` and
+			// `/path` are different routes.
+			continue
+		}
+		if token[0] == '*' {
+			if i+1 < lpath {
+				return nil, fmt.Errorf("catch-all must be the last segment of '%s'", route)
+			}
+			if len(token) == 1 {
+				return nil, fmt.Errorf("invalid parameter '' in '%s'", route)
+			}
+			parent = parent.addStatic(static)
+			if existing := parent.paramRoute(); existing != "" {
+				return nil, &ConflictError{Existing: existing}
+			}
+			if parent.catchAll != nil {
+				return nil, &ConflictError{Existing: parent.catchAll.route}
+			}
+			if hasName(names, string(token[1:])) {
+				return nil, fmt.Errorf("duplicate parameter '%s' in '%s'", token[1:], route)
+			}
+			node := newNode()
+			node.handler = handler
+			node.handlerName = handlerName
+			node.names = append(names, string(token[1:]))
+			node.route = route
+			node.path = "/" + path
+			parent.catchAll = node
+			return func() {
+				parent.catchAll = nil
+			}, nil
+		}
+		for len(token) > 0 {
+			j := bytes.IndexByte(token, ':')
+			if j < 0 {
+				static = append(static, token...)
+				break
+			}
+			static = append(static, token[:j]...)
+			parent = parent.addStatic(static)
+			static = static[:0]
+			if parent.catchAll != nil {
+				return nil, &ConflictError{Existing: parent.catchAll.route}
+			}
+			name, constraint, rest, err := parseParam(route, token[j+1:])
+			if err != nil {
+				return nil, err
+			}
+			if hasName(names, name) {
+				return nil, fmt.Errorf("duplicate parameter '%s' in '%s'", name, route)
+			}
+			if names == nil {
+				names = make([]string, 0)
+			}
+			names = append(names, name)
+			if constraint != "" {
+				if parent, err = parent.addConstrained(route, constraint, types); err != nil {
+					return nil, err
 				}
-				if names == nil {
-					names = make([]string, 0)
+			} else {
+				if parent.wildcard == nil {
+					parent.wildcard = newNode()
 				}
-				names = append(names, name)
-				parent = node
-				if i+1 >= lpath {
-					if !nodeCreated && node.handler != nil {
-						panic(fmt.Sprintf("conflict adding '%s'", path))
-					}
-					node.handler = handler
-					node.names = names
+				parent = parent.wildcard
+			}
+			if len(rest) > 0 {
+				// The parameter ends before a literal of its segment.
+				if rest[0] == ':' {
+					return nil, fmt.Errorf("ambiguous parameters in '%s'", route)
 				}
-				continue
-			} else {
-				spath := string(pathBytes[i])
-				node, ok := parent.children[spath]
-				if !ok {
-					node = newNode()
-					parent.children[spath] = node
+				parent.inSegment = true
+			}
+			token = rest
+		}
+	}
+	parent = parent.addStatic(static)
+	if parent.handler != nil {
+		return nil, &ConflictError{Existing: parent.route}
+	}
+	parent.handler = handler
+	parent.handlerName = handlerName
+	parent.names = names
+	parent.route = route
+	parent.path = "/" + path
+	return func() {
+		parent.handler = nil
+		parent.handlerName = ""
+		parent.names = nil
+		parent.route = ""
+		parent.path = ""
+	}, nil
+}
+
+// Walk calls `fn` for the nodes with a handler in the subtree of `n`, in
+// matching priority order, stopping at the first error.
+func (n *node) Walk(fn func(*node) error) error {
+	if n.handler != nil {
+		if err := fn(n); err != nil {
+			return err
+		}
+	}
+	for _, child := range n.children {
+		if err := child.Walk(fn); err != nil {
+			return err
+		}
+	}
+	for _, child := range n.constrained {
+		if err := child.Walk(fn); err != nil {
+			return err
+		}
+	}
+	if n.wildcard != nil {
+		if err := n.wildcard.Walk(fn); err != nil {
+			return err
+		}
+	}
+	if n.catchAll != nil {
+		return fn(n.catchAll)
+	}
+	return nil
+}
+
+// paramRoute returns a route registered through the constrained wildcards
+// or the wildcard of `n`, empty when there is none.
+func (n *node) paramRoute() string {
+	for _, child := range n.constrained {
+		if route := child.firstRoute(); route != "" {
+			return route
+		}
+	}
+	if n.wildcard != nil {
+		return n.wildcard.firstRoute()
+	}
+	return ""
+}
+
+// firstRoute returns a route registered in the subtree of `n`, empty when
+// there is none.
+func (n *node) firstRoute() string {
+	if n.handler != nil {
+		return n.route
+	}
+	for _, child := range n.children {
+		if route := child.firstRoute(); route != "" {
+			return route
+		}
+	}
+	if route := n.paramRoute(); route != "" {
+		return route
+	}
+	if n.catchAll != nil {
+		return n.catchAll.route
+	}
+	return ""
+}
+
+// parseParam splits a parameter token, after its `:`, into the name of the
+// parameter, its `{pattern}` or `<type>` constraint (delimiters included,
+// empty when it has none) and the rest of the token. The name is made of
+// letters, digits and underscores.
+func parseParam(route string, token []byte) (string, string, []byte, error) {
+	i := 0
+	for i < len(token) && isNameByte(token[i]) {
+		i++
+	}
+	if i == 0 {
+		return "", "", nil, fmt.Errorf("invalid parameter '%s' in '%s'", token, route)
+	}
+	name := string(token[:i])
+	if i == len(token) || (token[i] != '{' && token[i] != '<') {
+		return name, "", token[i:], nil
+	}
+	end := -1
+	if token[i] == '<' {
+		end = bytes.IndexByte(token[i:], '>')
+	} else {
+		depth := 0
+		for j := i; j < len(token) && end < 0; j++ {
+			switch token[j] {
+			case '{':
+				depth++
+			case '}':
+				depth--
+				if depth == 0 {
+					end = j - i
 				}
-				if i+1 < lpath {
-					parent = node
-				} else {
-					if ok && node.handler != nil {
-						panic(fmt.Sprintf("conflict adding '%s'", path))
+			}
+		}
+	}
+	if end < 2 {
+		return "", "", nil, fmt.Errorf("invalid parameter '%s' in '%s'", token, route)
+	}
+	return name, string(token[i : i+end+1]), token[i+end+1:], nil
+}
+
+// hasName reports whether `names` already holds the parameter `name`.
+func hasName(names []string, name string) bool {
+	for _, existing := range names {
+		if existing == name {
+			return true
+		}
+	}
+	return false
+}
+
+func isNameByte(c byte) bool {
+	return c == '_' || ('0' <= c && c <= '9') || ('a' <= c && c <= 'z') || ('A' <= c && c <= 'Z')
+}
+
+// addConstrained returns the constrained wildcard of `n` for `constraint`,
+// resolving its matcher when it is new: a `{pattern}` is compiled and a
+// `<type>` is looked up in `types`.
+func (n *node) addConstrained(route, constraint string, types map[string]func([]byte) bool) (*node, error) {
+	for _, child := range n.constrained {
+		if child.constraint == constraint {
+			return child, nil
+		}
+	}
+	inner := constraint[1 : len(constraint)-1]
+	child := newNode()
+	child.constraint = constraint
+	if constraint[0] == '<' {
+		child.match = types[inner]
+		if child.match == nil {
+			return nil, fmt.Errorf("unknown parameter type '%s' in '%s'", inner, route)
+		}
+	} else {
+		re, err := regexp.Compile("^(?:" + inner + ")$")
+		if err != nil {
+			return nil, fmt.Errorf("invalid pattern '%s' in '%s': %s", inner, route, err)
+		}
+		child.match = re.Match
+	}
+	n.constrained = append(n.constrained, child)
+	return child, nil
+}
+
+// addStatic returns the node matching the static bytes of `path` right after
+// `n`, splitting or creating nodes as needed.
+func (n *node) addStatic(path []byte) *node {
+	for len(path) > 0 {
+		i := bytes.IndexByte(n.indices, path[0])
+		if i < 0 {
+			child := newNode()
+			child.prefix = append([]byte(nil), path...)
+			n.indices = append(n.indices, path[0])
+			n.children = append(n.children, child)
+			return child
+		}
+		child := n.children[i]
+		l := commonPrefix(path, child.prefix)
+		if l < len(child.prefix) {
+			// The new node takes the place of `child`, which keeps its
+			// identity, so the undo functions of Add stay valid.
+			split := &node{
+				prefix:   child.prefix[:l],
+				indices:  []byte{child.prefix[l]},
+				children: []*node{child},
+			}
+			child.prefix = child.prefix[l:]
+			n.children[i] = split
+			child = split
+		}
+		n = child
+		path = path[l:]
+	}
+	return n
+}
+
+func commonPrefix(a, b []byte) int {
+	i := 0
+	for i < len(a) && i < len(b) && a[i] == b[i] {
+		i++
+	}
+	return i
+}
+
+// Matches resolves `path`, the request path without its leading slash,
+// against the subtree after the prefix of `n`. Static children have
+// priority; the constrained wildcards, the wildcard and then the catch-all
+// are tried when they fail.
+func (n *node) Matches(path []byte, values [][]byte, caseInsensitive bool) (bool, *node, [][]byte) {
+	if len(path) == 0 {
+		if n.handler != nil {
+			return true, n, values
+		}
+		if catchAll := n.emptyCatchAll(); catchAll != nil {
+			return true, catchAll, append(values, nil)
+		}
+		return false, nil, nil
+	}
+	for i, c := range n.indices {
+		if c == path[0] {
+			child := n.children[i]
+			if bytes.HasPrefix(path, child.prefix) {
+				if found, node, values := child.Matches(path[len(child.prefix):], values, caseInsensitive); found {
+					return true, node, values
+				}
+			} else if child.catchAll != nil && len(child.prefix) == len(path)+1 && bytes.HasPrefix(child.prefix, path) && child.prefix[len(path)] == '/' {
+				return true, child.catchAll, append(values, nil)
+			}
+			break
+		}
+	}
+	if caseInsensitive {
+		for _, child := range n.children {
+			if len(path) >= len(child.prefix) && !bytes.HasPrefix(path, child.prefix) && equalFold(child.prefix, path[:len(child.prefix)]) {
+				if found, node, values := child.Matches(path[len(child.prefix):], values, caseInsensitive); found {
+					return true, node, values
+				}
+			}
+		}
+	}
+	if n.wildcard != nil || len(n.constrained) > 0 {
+		end := bytes.IndexByte(path, '/')
+		if end < 0 {
+			end = len(path)
+		}
+		if end > 0 {
+			for _, child := range n.constrained {
+				for i := child.valueEnd(path, 1, end, caseInsensitive); i <= end; i = child.valueEnd(path, i+1, end, caseInsensitive) {
+					if child.match(path[:i]) {
+						if found, node, values := child.Matches(path[i:], append(values, path[:i]), caseInsensitive); found {
+							return true, node, values
+						}
+					}
+				}
+			}
+			if n.wildcard != nil {
+				for i := n.wildcard.valueEnd(path, 1, end, caseInsensitive); i <= end; i = n.wildcard.valueEnd(path, i+1, end, caseInsensitive) {
+					if found, node, values := n.wildcard.Matches(path[i:], append(values, path[:i]), caseInsensitive); found {
+						return true, node, values
 					}
-					node.handler = handler
-					node.names = names
-					return
 				}
 			}
-		} else if i+1 < lpath {
-			panic("empty token")
-		} else {
-			n.handler = handler
-			n.names = names
 		}
 	}
+	if n.catchAll != nil {
+		return true, n.catchAll, append(values, path)
+	}
+	return false, nil, nil
 }
 
-func (n *node) Matches(path [][]byte, values [][]byte) (bool, *node, [][]byte) {
-	lpath := len(path)
-	for i := 0; i < lpath; i++ {
-		token := string(path[i])
-		node, ok := n.children[token]
-		if ok {
-			if i+1 < lpath {
-				return node.Matches(path[i+1:], values)
-			} else if node.handler == nil {
-				return false, nil, nil
-			} else {
-				return true, node, values
+// valueEnd returns the first position, from `from` up to `end`, the end of
+// the segment, where the value of the wildcard `n` may end. Past `end`, it
+// returns `end+1` to stop the iteration.
+func (n *node) valueEnd(path []byte, from, end int, caseInsensitive bool) int {
+	if from > end {
+		return end + 1
+	}
+	if n.inSegment {
+		for i := from; i < end; i++ {
+			for _, c := range n.indices {
+				if c == path[i] || (caseInsensitive && foldByte(c) == foldByte(path[i])) {
+					return i
+				}
 			}
-		} else if n.wildcard != nil {
-			if values == nil {
-				values = [][]byte{path[i]}
-			} else {
-				values = append(values, path[i])
+		}
+	}
+	return end
+}
+
+// emptyCatchAll returns the catch-all matching an empty remainder once the
+// path ended at `n`: its own or, as `/static` does for `/static/*filepath`,
+// the one right after a slash.
+func (n *node) emptyCatchAll() *node {
+	if n.catchAll != nil {
+		return n.catchAll
+	}
+	if i := bytes.IndexByte(n.indices, '/'); i >= 0 && len(n.children[i].prefix) == 1 {
+		return n.children[i].catchAll
+	}
+	return nil
+}
+
+// FixCase works as a case insensitive Matches but, instead of the values,
+// returns `path` with the static bytes in their registered case.
+func (n *node) FixCase(path []byte, fixed []byte) (bool, []byte) {
+	if len(path) == 0 {
+		return n.handler != nil || n.emptyCatchAll() != nil, fixed
+	}
+	for i, c := range n.indices {
+		if c == path[0] {
+			child := n.children[i]
+			if bytes.HasPrefix(path, child.prefix) {
+				if found, fixed := child.FixCase(path[len(child.prefix):], append(fixed, child.prefix...)); found {
+					return true, fixed
+				}
 			}
-			if i+1 < lpath {
-				return n.wildcard.Matches(path[i+1:], values)
-			} else if n.wildcard.handler == nil {
-				return false, nil, nil
-			} else {
-				return true, n.wildcard, values
+			break
+		}
+	}
+	for _, child := range n.children {
+		if len(path) >= len(child.prefix) && !bytes.HasPrefix(path, child.prefix) && equalFold(child.prefix, path[:len(child.prefix)]) {
+			if found, fixed := child.FixCase(path[len(child.prefix):], append(fixed, child.prefix...)); found {
+				return true, fixed
 			}
-		} else {
-			return false, nil, nil
 		}
 	}
-	return false, nil, nil
+	if n.wildcard != nil || len(n.constrained) > 0 {
+		end := bytes.IndexByte(path, '/')
+		if end < 0 {
+			end = len(path)
+		}
+		if end > 0 {
+			for _, child := range n.constrained {
+				for i := child.valueEnd(path, 1, end, true); i <= end; i = child.valueEnd(path, i+1, end, true) {
+					if child.match(path[:i]) {
+						if found, fixed := child.FixCase(path[i:], append(fixed, path[:i]...)); found {
+							return true, fixed
+						}
+					}
+				}
+			}
+			if n.wildcard != nil {
+				for i := n.wildcard.valueEnd(path, 1, end, true); i <= end; i = n.wildcard.valueEnd(path, i+1, end, true) {
+					if found, fixed := n.wildcard.FixCase(path[i:], append(fixed, path[:i]...)); found {
+						return true, fixed
+					}
+				}
+			}
+		}
+	}
+	if n.catchAll != nil {
+		return true, append(fixed, path...)
+	}
+	return false, nil
+}
+
+// equalFold reports whether `a` and `b` are equal under ASCII case folding.
+func equalFold(a, b []byte) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := 0; i < len(a); i++ {
+		if a[i] != b[i] && foldByte(a[i]) != foldByte(b[i]) {
+			return false
+		}
+	}
+	return true
+}
+
+// foldByte returns the lower case of an ASCII letter, or `c` itself.
+func foldByte(c byte) byte {
+	if 'A' <= c && c <= 'Z' {
+		return c + 'a' - 'A'
+	}
+	return c
 }
@@ -0,0 +1,6 @@
+//go:build !race
+// +build !race
+
+package fasthttp_router
+
+const raceEnabled = false
@@ -0,0 +1,48 @@
+package fasthttp_router
+
+import "github.com/valyala/fasthttp"
+
+// ParamsKey is the user value key the Params of a request are stored under.
+const ParamsKey = "fasthttp_router.params"
+
+// Param is a parameter captured from the path of a request.
+type Param struct {
+	Key   string
+	Value []byte
+}
+
+// Params is the list of parameters captured from the path of a request, in
+// the order they appear in the route.
+//
+// The values point to buffers reused across requests, so they are only
+// valid until the handler returns. Copy them to keep them for longer.
+type Params []Param
+
+// ByName returns the value of the parameter `name`, or nil when there is no
+// such parameter.
+func (ps Params) ByName(name string) []byte {
+	for i := range ps {
+		if ps[i].Key == name {
+			return ps[i].Value
+		}
+	}
+	return nil
+}
+
+// Get returns the value of the i-th parameter, or nil when out of range.
+func (ps Params) Get(i int) []byte {
+	if i < 0 || i >= len(ps) {
+		return nil
+	}
+	return ps[i].Value
+}
+
+// GetParams returns the Params of the request being handled. They are only
+// available while the handler of the route runs: GetParams returns nil
+// afterwards, in the middlewares of Use too.
+func GetParams(ctx *fasthttp.RequestCtx) Params {
+	if ps, ok := ctx.UserValue(ParamsKey).(*Params); ok {
+		return *ps
+	}
+	return nil
+}
@@ -0,0 +1,8 @@
+//go:build race
+// +build race
+
+package fasthttp_router
+
+// raceEnabled reports whether the tests run under the race detector, which
+// randomly drops the items of sync.Pool.
+const raceEnabled = true
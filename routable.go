@@ -3,13 +3,18 @@ package fasthttp_router
 import "github.com/valyala/fasthttp"
 
 type Routable interface {
-	DELETE(path string, handler fasthttp.RequestHandler)
-	GET(path string, handler fasthttp.RequestHandler)
-	HEAD(path string, handler fasthttp.RequestHandler)
-	OPTIONS(path string, handler fasthttp.RequestHandler)
-	PATCH(path string, handler fasthttp.RequestHandler)
-	POST(path string, handler fasthttp.RequestHandler)
-	PUT(path string, handler fasthttp.RequestHandler)
+	DELETE(path string, handler fasthttp.RequestHandler, middlewares ...Middleware) *Route
+	GET(path string, handler fasthttp.RequestHandler, middlewares ...Middleware) *Route
+	HEAD(path string, handler fasthttp.RequestHandler, middlewares ...Middleware) *Route
+	OPTIONS(path string, handler fasthttp.RequestHandler, middlewares ...Middleware) *Route
+	PATCH(path string, handler fasthttp.RequestHandler, middlewares ...Middleware) *Route
+	POST(path string, handler fasthttp.RequestHandler, middlewares ...Middleware) *Route
+	PUT(path string, handler fasthttp.RequestHandler, middlewares ...Middleware) *Route
+
+	Handle(method, path string, handler fasthttp.RequestHandler, middlewares ...Middleware) *Route
+	TryHandle(method, path string, handler fasthttp.RequestHandler, middlewares ...Middleware) error
+	Any(path string, handler fasthttp.RequestHandler, middlewares ...Middleware) *Route
 
 	Group(path string, middlewares ...Middleware) Routable
+	Use(middlewares ...Middleware)
 }
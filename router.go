@@ -2,81 +2,374 @@ package fasthttp_router
 
 import (
 	"github.com/valyala/fasthttp"
+	"errors"
 	"fmt"
 	"sync"
 	"bytes"
+	"net/url"
+	"path"
+	"sort"
+	"strings"
 )
 
 type Middleware func(handler fasthttp.RequestHandler) fasthttp.RequestHandler
 
+// Middlewares wraps `handler` with `middlewares`, the first one being the
+// outermost: `Middlewares(h, a, b)` is `a(b(h))`, so `a` runs first.
 func Middlewares(handler fasthttp.RequestHandler, middlewares ...Middleware) fasthttp.RequestHandler {
 	result := handler
-	for i := 0; i < len(middlewares); i++ {
+	for i := len(middlewares) - 1; i >= 0; i-- {
 		result = middlewares[i](result)
 	}
 	return result
 }
 
+var anyMethods = []string{"CONNECT", "DELETE", "GET", "HEAD", "OPTIONS", "PATCH", "POST", "PUT", "TRACE"}
+
 type Router struct {
-	children map[string]*node
-	NotFound fasthttp.RequestHandler
+	trees       [methodCount]*node
+	custom      map[string]*node
+	paramTypes  map[string]func([]byte) bool
+	hosts       []*hostRoute
+	parent      *Router
+	errs        Errors
+	names       map[string]string
+	middlewares []Middleware
+	handler     fasthttp.RequestHandler
+	mounts      []*mount
+	NotFound    fasthttp.RequestHandler
+
+	// HandleMethodNotAllowed enables answering with 405 Method Not Allowed,
+	// and the matching `Allow` header, when the path has no route for the
+	// requested method but has for others. Enabled by default.
+	HandleMethodNotAllowed bool
+
+	// MethodNotAllowed is called, with the status and `Allow` header already
+	// set, when HandleMethodNotAllowed is on. When nil, a plain 405 response
+	// is sent.
+	MethodNotAllowed fasthttp.RequestHandler
+
+	// HandleHEAD enables serving HEAD requests with the GET handler of the
+	// path, without the body, when no HEAD route was registered for it.
+	// Enabled by default.
+	HandleHEAD bool
+
+	// RedirectTrailingSlash enables redirecting to the path with the trailing
+	// slash added or removed when only that version has a route.
+	RedirectTrailingSlash bool
+
+	// RedirectFixedPath enables redirecting requests for non canonical paths,
+	// such as `/a//b/../c`, to their clean version when it has a route.
+	RedirectFixedPath bool
+
+	// UseRawPath enables routing on the path as sent by the client, before
+	// percent-decoding, so an encoded `/` does not split a token. Static
+	// tokens are then compared as sent, and each captured value is
+	// percent-decoded on its own.
+	UseRawPath bool
+
+	// UserValues enables setting each parameter as a string user value of
+	// the request, besides the Params. Disable it to route without
+	// allocations. Enabled by default.
+	UserValues bool
+
+	// CaseInsensitive enables matching the static tokens of the routes
+	// ignoring their (ASCII) case. Parameters keep the requested case.
+	CaseInsensitive bool
+
+	// RedirectFixedCase enables redirecting requests matching a route only
+	// when ignoring the case to the path with the registered case.
+	RedirectFixedCase bool
+
+	// HandleOPTIONS enables answering OPTIONS requests automatically, with
+	// the `Allow` header listing the methods of the path, when no OPTIONS
+	// route was registered for it.
+	HandleOPTIONS bool
+
+	// GlobalOPTIONS is called, with the `Allow` header already set, for the
+	// automatic OPTIONS responses. It is the place to answer CORS preflights.
+	GlobalOPTIONS fasthttp.RequestHandler
+
+	// LegacyMiddlewareOrder restores the order middlewares had before being
+	// fixed, for compatibility: each list of middlewares, given to Use, Group
+	// or a route, runs from the last one to the first one. Lists still run
+	// from the router to the route. Middlewares itself is not affected. Set
+	// it before adding routes and middlewares.
+	LegacyMiddlewareOrder bool
+
+	// CollectErrors enables collecting the registration errors of Handle,
+	// and of the methods relying on it, instead of panicking. They are
+	// reported by Err.
+	CollectErrors bool
 }
 
 func New() *Router {
 	return &Router{
-		children: make(map[string]*node),
+		custom:                 make(map[string]*node),
+		paramTypes:             defaultParamTypes(),
+		HandleMethodNotAllowed: true,
+		HandleHEAD:             true,
+		UserValues:             true,
+	}
+}
+
+// Handle registers `handler`, wrapped by the route `middlewares`, for the
+// requests of `method` to `path`, and returns the Route to name it. Route
+// middlewares run after those of the router and the groups, the first one
+// first. It panics when the route is invalid or conflicts with an existing
+// one, unless CollectErrors is on.
+func (router *Router) Handle(method, path string, handler fasthttp.RequestHandler, middlewares ...Middleware) *Route {
+	return router.register(method, path, handler, router.ordered(middlewares))
+}
+
+// register adds the route, wrapped by the `chain` of middlewares, outermost
+// first, failing on errors.
+func (router *Router) register(method, path string, handler fasthttp.RequestHandler, chain []Middleware) *Route {
+	if err := router.handle(method, path, handler, chain); err != nil {
+		router.fail(err)
+	}
+	if len(path) == 0 || path[0] != '/' {
+		path = "/" + path
+	}
+	return &Route{
+		router:  router.owner(),
+		pattern: path,
 	}
 }
 
-func (router *Router) handle(method, path string, handler fasthttp.RequestHandler) {
-	root, ok := router.children[method]
-	if !ok {
+// ordered returns a copy of `middlewares` in the order they run, reversed
+// when LegacyMiddlewareOrder is on.
+func (router *Router) ordered(middlewares []Middleware) []Middleware {
+	result := make([]Middleware, len(middlewares))
+	if router.owner().LegacyMiddlewareOrder {
+		for i, middleware := range middlewares {
+			result[len(middlewares)-1-i] = middleware
+		}
+	} else {
+		copy(result, middlewares)
+	}
+	return result
+}
+
+// owner returns the router the host routes of `router` belong to, or the
+// router itself.
+func (router *Router) owner() *Router {
+	for router.parent != nil {
+		router = router.parent
+	}
+	return router
+}
+
+// fail panics with the registration error `err`, or collects it when
+// CollectErrors is on.
+func (router *Router) fail(err error) {
+	owner := router.owner()
+	if !owner.CollectErrors {
+		panic(err)
+	}
+	owner.errs = append(owner.errs, err)
+}
+
+// TryHandle works as Handle but returns the error, a *ConflictError for
+// conflicting routes, instead of panicking. Nothing is registered when it
+// fails.
+func (router *Router) TryHandle(method, path string, handler fasthttp.RequestHandler, middlewares ...Middleware) error {
+	return router.handle(method, path, handler, router.ordered(middlewares))
+}
+
+// handle adds the route, wrapped by the `chain` of middlewares, outermost
+// first, and by those of Use for host routes.
+func (router *Router) handle(method, path string, handler fasthttp.RequestHandler, chain []Middleware) error {
+	if method == "" {
+		return errors.New("empty method")
+	}
+	if router.parent != nil {
+		chain = append(router.ordered(router.middlewares), chain...)
+	}
+	name := handlerName(handler)
+	handler = Middlewares(handler, chain...)
+	root := router.tree(method)
+	if root == nil {
 		root = newNode()
-		router.children[method] = root
+		if i := methodIndex([]byte(method)); i >= 0 {
+			router.trees[i] = root
+		} else {
+			router.custom[method] = root
+		}
 	}
 
 	if len(path) > 0 && path[0] == '/' {
 		path = path[1:]
 	}
-	root.Add(path, handler, nil)
+	route := "/" + path
+	variants := expandOptional(path)
+	undos := make([]func(), 0, len(variants))
+	for _, variant := range variants {
+		undo, err := root.Add(variant, handler, name, route, router.paramTypes)
+		if err != nil {
+			for _, undo := range undos {
+				undo()
+			}
+			if conflict, ok := err.(*ConflictError); ok {
+				conflict.Method = method
+				conflict.Pattern = route
+			}
+			return err
+		}
+		undos = append(undos, undo)
+	}
+	return nil
+}
+
+// Err returns the Errors collected by Handle when CollectErrors is on, or nil
+// when there are none.
+func (router *Router) Err() error {
+	if len(router.errs) == 0 {
+		return nil
+	}
+	return router.errs
+}
+
+// optionalSegment returns `segment` without its optional marker, and
+// whether it had one.
+func optionalSegment(segment string) (string, bool) {
+	if l := len(segment); l > 1 && segment[l-1] == '?' {
+		return segment[:l-1], true
+	} else if l > 2 && segment[0] == '(' && segment[l-1] == ')' {
+		return segment[1 : l-1], true
+	}
+	return segment, false
+}
+
+// expandOptional returns the variants of `path` with and without each of
+// its optional segments, those ending with `?` (`:page?`) or wrapped in
+// parentheses (`(:lang)`), the most complete variant first.
+func expandOptional(path string) []string {
+	variants := [][]string{nil}
+	for _, segment := range strings.Split(path, "/") {
+		segment, optional := optionalSegment(segment)
+		expanded := make([][]string, 0, 2*len(variants))
+		for _, variant := range variants {
+			expanded = append(expanded, append(variant[:len(variant):len(variant)], segment))
+		}
+		if optional {
+			expanded = append(expanded, variants...)
+		}
+		variants = expanded
+	}
+	result := make([]string, len(variants))
+	for i, variant := range variants {
+		result[i] = strings.Join(variant, "/")
+	}
+	return result
+}
+
+// RegisterParamType registers the `name` type for `:param<name>` tokens,
+// accepting the segments `matcher` returns true for. Types are resolved when
+// routes are added, so they must be registered before the routes using
+// them. The built-in `int`, `uuid` and `alpha` types can be replaced.
+func (router *Router) RegisterParamType(name string, matcher func([]byte) bool) {
+	if name == "" || matcher == nil {
+		panic("invalid parameter type")
+	}
+	router.paramTypes[name] = matcher
+}
+
+// tree returns the root of the routes of `method`, nil when there are none.
+func (router *Router) tree(method string) *node {
+	if i := methodIndex([]byte(method)); i >= 0 {
+		return router.trees[i]
+	}
+	return router.custom[method]
 }
 
-func (router *Router) DELETE(path string, handler fasthttp.RequestHandler) {
-	router.handle("DELETE", path, handler)
+func (router *Router) DELETE(path string, handler fasthttp.RequestHandler, middlewares ...Middleware) *Route {
+	return router.Handle("DELETE", path, handler, middlewares...)
 }
 
-func (router *Router) GET(path string, handler fasthttp.RequestHandler) {
-	router.handle("GET", path, handler)
+func (router *Router) GET(path string, handler fasthttp.RequestHandler, middlewares ...Middleware) *Route {
+	return router.Handle("GET", path, handler, middlewares...)
 }
 
-func (router *Router) POST(path string, handler fasthttp.RequestHandler) {
-	router.handle("POST", path, handler)
+func (router *Router) POST(path string, handler fasthttp.RequestHandler, middlewares ...Middleware) *Route {
+	return router.Handle("POST", path, handler, middlewares...)
 }
 
-func (router *Router) PUT(path string, handler fasthttp.RequestHandler) {
-	router.handle("PUT", path, handler)
+func (router *Router) PUT(path string, handler fasthttp.RequestHandler, middlewares ...Middleware) *Route {
+	return router.Handle("PUT", path, handler, middlewares...)
 }
 
-func (router *Router) HEAD(path string, handler fasthttp.RequestHandler) {
-	router.handle("HEAD", path, handler)
+func (router *Router) HEAD(path string, handler fasthttp.RequestHandler, middlewares ...Middleware) *Route {
+	return router.Handle("HEAD", path, handler, middlewares...)
 }
 
-func (router *Router) OPTIONS(path string, handler fasthttp.RequestHandler) {
-	router.handle("OPTIONS", path, handler)
+func (router *Router) OPTIONS(path string, handler fasthttp.RequestHandler, middlewares ...Middleware) *Route {
+	return router.Handle("OPTIONS", path, handler, middlewares...)
 }
 
-func (router *Router) PATCH(path string, handler fasthttp.RequestHandler) {
-	router.handle("PATCH", path, handler)
+func (router *Router) PATCH(path string, handler fasthttp.RequestHandler, middlewares ...Middleware) *Route {
+	return router.Handle("PATCH", path, handler, middlewares...)
 }
 
+// Any registers the handler for all the standard methods.
+func (router *Router) Any(path string, handler fasthttp.RequestHandler, middlewares ...Middleware) *Route {
+	var route *Route
+	for _, method := range anyMethods {
+		route = router.Handle(method, path, handler, middlewares...)
+	}
+	return route
+}
+
+// Group returns the Routable adding routes under the `path` prefix, wrapped
+// by `middlewares`. The prefix is joined to the route paths segment wise, so
+// `/api/`, `/api` and `api` are the same prefix, and may have parameters. It
+// panics when the prefix is invalid, unless CollectErrors is on.
 func (router *Router) Group(path string, middlewares ... Middleware) Routable {
 	return &routerGroup{
-		prefix:      path,
-		router:      router,
+		prefix:      router.groupPrefix(path),
+		root:        router,
 		middlewares: middlewares,
 	}
 }
 
+// groupPrefix returns `path` as a group prefix: rooted, without trailing
+// slashes, and empty for the root. Invalid prefixes fail.
+func (router *Router) groupPrefix(path string) string {
+	path = strings.Trim(path, "/")
+	if path == "" {
+		return ""
+	}
+	segments := strings.Split(path, "/")
+	for i, segment := range segments {
+		segments[i], _ = optionalSegment(segment)
+		if segments[i] == "" {
+			router.fail(fmt.Errorf("empty token in group prefix '/%s'", path))
+			return "/" + path
+		}
+		if segments[i][0] == '*' {
+			router.fail(fmt.Errorf("catch-all in group prefix '/%s'", path))
+			return "/" + path
+		}
+	}
+	// The parameters are checked by adding the prefix to a scratch tree.
+	if _, err := newNode().Add(strings.Join(segments, "/"), nil, "", "/"+path, router.paramTypes); err != nil {
+		router.fail(err)
+	}
+	return "/" + path
+}
+
+// joinPath joins the group `prefix` and the route `path`, keeping the
+// trailing slash of `path`.
+func joinPath(prefix, path string) string {
+	if path == "" {
+		return prefix
+	}
+	if path[0] != '/' {
+		path = "/" + path
+	}
+	return prefix + path
+}
+
 func Split(source []byte, dest [][]byte) [][]byte {
 	lSource := len(source)
 	s := 0
@@ -97,95 +390,500 @@ func Split(source []byte, dest [][]byte) [][]byte {
 	return dest
 }
 
-var routerHandlerSep = []byte{'/'}
+// routePath returns `path` without its leading slash, as the trees match it.
+func routePath(path []byte) []byte {
+	if len(path) > 0 && path[0] == '/' {
+		return path[1:]
+	}
+	return path
+}
 
-var pathPool = sync.Pool{
+// CleanPath returns the canonical version of `p`: rooted, without empty, `.`
+// and `..` segments. The trailing slash, if any, is kept.
+func CleanPath(p string) string {
+	if p == "" {
+		return "/"
+	}
+	if p[0] != '/' {
+		p = "/" + p
+	}
+	cleaned := path.Clean(p)
+	if p[len(p)-1] == '/' && cleaned != "/" {
+		cleaned += "/"
+	}
+	return cleaned
+}
+
+func toggleTrailingSlash(path string) string {
+	if path == "/" || path == "" {
+		return path
+	}
+	if path[len(path)-1] == '/' {
+		return path[:len(path)-1]
+	}
+	return path + "/"
+}
+
+// requestState holds the buffers used to route a request, reused across
+// requests.
+type requestState struct {
+	values [][]byte
+	params Params
+	buf    []byte
+}
+
+var statePool = sync.Pool{
 	New: func() interface{} {
-		return make([][]byte, 0)
+		return &requestState{}
 	},
 }
 
+// releaseState returns `state` to the pool, removing the Params pointing to
+// it from `ctx` so they cannot be read once reused by another request.
+func releaseState(ctx *fasthttp.RequestCtx, state *requestState) {
+	if ps, ok := ctx.UserValue(ParamsKey).(*Params); ok && ps == &state.params {
+		ctx.SetUserValue(ParamsKey, nil)
+	}
+	statePool.Put(state)
+}
+
+// Handler is the fasthttp.RequestHandler routing the requests, through the
+// middlewares of Use.
 func (router *Router) Handler(ctx *fasthttp.RequestCtx) {
-	method := string(ctx.Method())
-	node, ok := router.children[method]
-	if ok {
-		path := pathPool.Get().([][]byte)
-		path = Split(ctx.Request.URI().Path(), path)
-		defer func() {
-			path = path[0:0]
-			pathPool.Put(path)
-		}()
-			path = bytes.Split(ctx.Request.URI().Path()[1:], routerHandlerSep)
-		/*
-		var path [][]byte
-		if ctx.Request.URI().Path()[0] == '/' {
-			path = bytes.Split(ctx.Request.URI().Path()[1:], routerHandlerSep)
-		} else {
-			path = bytes.Split(ctx.Request.URI().Path(), routerHandlerSep)
+	if router.handler != nil {
+		router.handler(ctx)
+		return
+	}
+	router.serve(ctx)
+}
+
+// Use adds middlewares run for every request, before it is routed, so they
+// also wrap the NotFound, method not allowed, OPTIONS and redirect
+// responses. They run in the order they were added, before the middlewares
+// of the groups and the routes.
+//
+// On the Routable of a Host, which has no requests of its own, Use works as
+// on groups: it wraps the handlers of the routes added afterwards.
+func (router *Router) Use(middlewares ...Middleware) {
+	router.middlewares = append(router.middlewares, middlewares...)
+	if router.parent == nil {
+		router.handler = Middlewares(router.serve, router.ordered(router.middlewares)...)
+	}
+}
+
+// serve routes the requests.
+func (router *Router) serve(ctx *fasthttp.RequestCtx) {
+	var method string
+	var root *node
+	if i := methodIndex(ctx.Method()); i >= 0 {
+		method = methodNames[i]
+		root = router.trees[i]
+	} else {
+		method = string(ctx.Method())
+		root = router.custom[method]
+	}
+
+	state := statePool.Get().(*requestState)
+	defer releaseState(ctx, state)
+	path := routePath(router.requestPath(ctx.Request.URI()))
+
+	if len(router.hosts) > 0 && router.dispatchHost(ctx, method, path, state) {
+		return
+	}
+
+	if router.RedirectFixedPath && method != "CONNECT" {
+		if location, ok := router.fixedPath(ctx, method); ok {
+			redirect(ctx, method, location)
+			return
 		}
-		*/
-		if len(path) == 1 && len(path[0]) == 0 {
-			if node.handler != nil {
-				node.handler(ctx)
-				return
-			}
-			if router.NotFound != nil {
-				router.NotFound(ctx)
+	}
+
+	if router.dispatch(ctx, root, path, state, nil) {
+		return
+	}
+
+	if method == "HEAD" && router.HandleHEAD {
+		ctx.Response.SkipBody = true
+		if router.dispatch(ctx, router.trees[methodGET], path, state, nil) {
+			return
+		}
+		ctx.Response.SkipBody = false
+	}
+
+	if len(router.mounts) > 0 && router.dispatchMount(ctx, state) {
+		return
+	}
+
+	if router.RedirectTrailingSlash && method != "CONNECT" {
+		uri := ctx.Request.URI()
+		if toggled := toggleTrailingSlash(string(router.requestPath(uri))); router.routed(ctx, method, []byte(toggled)) {
+			// The location is cleaned so it never starts with `//`, which
+			// would redirect to another host.
+			redirect(ctx, method, toggleTrailingSlash(CleanPath(string(uri.PathOriginal()))))
+			return
+		}
+	}
+
+	if router.RedirectFixedCase && method != "CONNECT" {
+		if location, ok := router.fixedCase(ctx, method, path); ok {
+			redirect(ctx, method, location)
+			return
+		}
+	}
+
+	if method == "OPTIONS" && router.HandleOPTIONS {
+		if allow := router.allowed(ctx, method, path); len(allow) > 0 {
+			ctx.Response.Header.Set("Allow", allow)
+			if router.GlobalOPTIONS != nil {
+				router.GlobalOPTIONS(ctx)
 			}
 			return
 		}
-		found, node, values := node.Matches(path, nil)
-		if found {
-			for i, v := range values {
-				ctx.SetUserValue(node.names[i], string(v))
+	} else if router.HandleMethodNotAllowed {
+		if allow := router.allowed(ctx, method, path); len(allow) > 0 {
+			if router.MethodNotAllowed != nil {
+				ctx.SetStatusCode(fasthttp.StatusMethodNotAllowed)
+				ctx.Response.Header.Set("Allow", allow)
+				router.MethodNotAllowed(ctx)
+			} else {
+				ctx.Error(fasthttp.StatusMessage(fasthttp.StatusMethodNotAllowed), fasthttp.StatusMethodNotAllowed)
+				ctx.Response.Header.Set("Allow", allow)
 			}
-			node.handler(ctx)
 			return
 		}
 	}
+
 	if router.NotFound != nil {
 		router.NotFound(ctx)
 	}
 }
 
+// requestPath returns the path the request is routed on.
+func (router *Router) requestPath(uri *fasthttp.URI) []byte {
+	if router.UseRawPath {
+		return uri.PathOriginal()
+	}
+	return uri.Path()
+}
+
+// appendUnescaped appends the percent-decoded `value` to `dst`. Malformed
+// escapes are kept as sent.
+func appendUnescaped(dst, value []byte) []byte {
+	for i := 0; i < len(value); i++ {
+		if value[i] == '%' && i+2 < len(value) && isHex(value[i+1]) && isHex(value[i+2]) {
+			dst = append(dst, unhex(value[i+1])<<4|unhex(value[i+2]))
+			i += 2
+			continue
+		}
+		dst = append(dst, value[i])
+	}
+	return dst
+}
+
+func isHex(c byte) bool {
+	return '0' <= c && c <= '9' || 'a' <= c && c <= 'f' || 'A' <= c && c <= 'F'
+}
+
+func unhex(c byte) byte {
+	switch {
+	case '0' <= c && c <= '9':
+		return c - '0'
+	case 'a' <= c && c <= 'f':
+		return c - 'a' + 10
+	}
+	return c - 'A' + 10
+}
+
+// fixedPath returns the location of the clean version of the requested
+// path, when the request is not already using it and the clean version (or
+// its trailing slash toggled) is routed.
+func (router *Router) fixedPath(ctx *fasthttp.RequestCtx, method string) (string, bool) {
+	uri := ctx.Request.URI()
+	if !unclean(uri.PathOriginal()) {
+		return "", false
+	}
+	original := string(uri.PathOriginal())
+	location := CleanPath(original)
+	if location == original {
+		return "", false
+	}
+	path := CleanPath(string(router.requestPath(uri)))
+	if router.routed(ctx, method, []byte(path)) {
+		return location, true
+	}
+	if router.RedirectTrailingSlash && router.routed(ctx, method, []byte(toggleTrailingSlash(path))) {
+		return toggleTrailingSlash(location), true
+	}
+	return "", false
+}
+
+// unclean reports whether `path` may differ from its CleanPath version:
+// when it is not rooted or has empty, `.` or `..` segments. It does not
+// allocate, unlike CleanPath.
+func unclean(path []byte) bool {
+	if len(path) == 0 || path[0] != '/' {
+		return true
+	}
+	for i := 0; i < len(path); i++ {
+		if path[i] != '/' {
+			continue
+		}
+		rest := path[i+1:]
+		if len(rest) > 0 && rest[0] == '/' {
+			return true
+		}
+		if len(rest) > 0 && rest[0] == '.' {
+			if len(rest) == 1 || rest[1] == '/' {
+				return true
+			}
+			if rest[1] == '.' && (len(rest) == 2 || rest[2] == '/') {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// fixedCase returns the location of the registered path matching `path`
+// case insensitively, when its case differs from the requested one. The
+// routes of the hosts matching the request are tried first.
+func (router *Router) fixedCase(ctx *fasthttp.RequestCtx, method string, path []byte) (string, bool) {
+	var fixed []byte
+	found := false
+	for _, routes := range router.hostRoutes(ctx) {
+		if found, fixed = router.fixedCaseIn(routes, method, path); found {
+			break
+		}
+	}
+	if !found {
+		found, fixed = router.fixedCaseIn(router, method, path)
+	}
+	if !found || bytes.Equal(fixed, path) {
+		return "", false
+	}
+	if router.UseRawPath {
+		// The raw path is already escaped.
+		return "/" + string(fixed), true
+	}
+	return (&url.URL{Path: "/" + string(fixed)}).EscapedPath(), true
+}
+
+// fixedCaseIn is the case insensitive lookup of fixedCase in the trees of
+// `routes`, the router or a host.
+func (router *Router) fixedCaseIn(routes *Router, method string, path []byte) (bool, []byte) {
+	root := routes.tree(method)
+	if root == nil && method == "HEAD" && router.HandleHEAD {
+		root = routes.trees[methodGET]
+	}
+	if root == nil {
+		return false, nil
+	}
+	return root.FixCase(path, nil)
+}
+
+// routed reports whether a request for `method` and `path` would reach a
+// handler, on the router or the hosts matching the request, considering the
+// HEAD fallback.
+func (router *Router) routed(ctx *fasthttp.RequestCtx, method string, path []byte) bool {
+	path = routePath(path)
+	if router.routedIn(router, method, path) {
+		return true
+	}
+	for _, routes := range router.hostRoutes(ctx) {
+		if router.routedIn(routes, method, path) {
+			return true
+		}
+	}
+	return false
+}
+
+// routedIn is routed for the trees of `routes`, the router or a host.
+func (router *Router) routedIn(routes *Router, method string, path []byte) bool {
+	if root := routes.tree(method); root != nil {
+		if found, _, _ := root.Matches(path, nil, router.CaseInsensitive); found {
+			return true
+		}
+	}
+	if method == "HEAD" && router.HandleHEAD {
+		if root := routes.trees[methodGET]; root != nil {
+			found, _, _ := root.Matches(path, nil, router.CaseInsensitive)
+			return found
+		}
+	}
+	return false
+}
+
+func redirect(ctx *fasthttp.RequestCtx, method, location string) {
+	code := fasthttp.StatusMovedPermanently
+	if method != "GET" {
+		code = fasthttp.StatusPermanentRedirect
+	}
+	if queryString := ctx.Request.URI().QueryString(); len(queryString) > 0 {
+		location = location + "?" + string(queryString)
+	}
+	ctx.Response.Header.Set("Location", location)
+	ctx.SetStatusCode(code)
+}
+
+// dispatch calls the handler registered in `root` for `path`, reporting
+// whether one was found. The values of the `hostNames`, when routing a host,
+// are expected at the start of `state.values`.
+func (router *Router) dispatch(ctx *fasthttp.RequestCtx, root *node, path []byte, state *requestState, hostNames []string) bool {
+	if root == nil {
+		return false
+	}
+	found, node, values := root.Matches(path, state.values[:len(hostNames)], router.CaseInsensitive)
+	if !found {
+		return false
+	}
+	state.values = values
+	state.params = state.params[:0]
+	state.buf = state.buf[:0]
+	for i, v := range values {
+		if router.UseRawPath {
+			start := len(state.buf)
+			state.buf = appendUnescaped(state.buf, v)
+			v = state.buf[start:]
+		}
+		var name string
+		if i < len(hostNames) {
+			name = hostNames[i]
+		} else {
+			name = node.names[i-len(hostNames)]
+		}
+		state.params = append(state.params, Param{Key: name, Value: v})
+		if router.UserValues {
+			ctx.SetUserValue(name, string(v))
+		}
+	}
+	ctx.SetUserValue(ParamsKey, &state.params)
+	node.handler(ctx)
+	return true
+}
+
+// allowed returns the comma separated list of methods, other than `method`,
+// that have a route matching `path`, on the router or the hosts matching the
+// request. HEAD and OPTIONS are listed when they are answered automatically.
+func (router *Router) allowed(ctx *fasthttp.RequestCtx, method string, path []byte) string {
+	methods := make([]string, 0, methodCount+len(router.custom)+1)
+	add := func(m string, root *node) {
+		if root == nil || m == method {
+			return
+		}
+		for _, listed := range methods {
+			if listed == m {
+				return
+			}
+		}
+		if found, _, _ := root.Matches(path, nil, router.CaseInsensitive); found {
+			methods = append(methods, m)
+		}
+	}
+	for _, routes := range append(router.hostRoutes(ctx), router) {
+		for i, root := range routes.trees {
+			add(methodNames[i], root)
+		}
+		for m, root := range routes.custom {
+			add(m, root)
+		}
+	}
+	hasGET, hasHEAD, hasOPTIONS := false, false, false
+	for _, m := range methods {
+		hasGET = hasGET || m == "GET"
+		hasHEAD = hasHEAD || m == "HEAD"
+		hasOPTIONS = hasOPTIONS || m == "OPTIONS"
+	}
+	if router.HandleHEAD && hasGET && !hasHEAD {
+		methods = append(methods, "HEAD")
+	}
+	if router.HandleOPTIONS && len(methods) > 0 && !hasOPTIONS {
+		methods = append(methods, "OPTIONS")
+	}
+	sort.Strings(methods)
+	return strings.Join(methods, ", ")
+}
+
 type routerGroup struct {
 	prefix      string
-	router      Routable
+	root        *Router
+	parent      *routerGroup
 	middlewares []Middleware
 }
 
-func (group *routerGroup) DELETE(path string, handler fasthttp.RequestHandler) {
-	group.router.DELETE(fmt.Sprintf("%s%s", group.prefix, path), Middlewares(handler, group.middlewares...))
+// path returns `path` with the prefixes of the group and its parents.
+func (group *routerGroup) path(path string) string {
+	for ; group != nil; group = group.parent {
+		path = joinPath(group.prefix, path)
+	}
+	return path
+}
+
+// chain returns the middlewares of a route of the group, outermost first:
+// those of the parent groups, of the group and the route `middlewares`.
+func (group *routerGroup) chain(middlewares []Middleware) []Middleware {
+	chain := group.root.ordered(middlewares)
+	for ; group != nil; group = group.parent {
+		chain = append(group.root.ordered(group.middlewares), chain...)
+	}
+	return chain
+}
+
+// Use adds middlewares wrapping the handlers of the routes, and subgroups
+// routes, added to the group afterwards. They run in the order they were
+// added, after the middlewares of the parent groups.
+func (group *routerGroup) Use(middlewares ...Middleware) {
+	group.middlewares = append(group.middlewares[:len(group.middlewares):len(group.middlewares)], middlewares...)
+}
+
+func (group *routerGroup) TryHandle(method, path string, handler fasthttp.RequestHandler, middlewares ...Middleware) error {
+	return group.root.handle(method, group.path(path), handler, group.chain(middlewares))
+}
+
+func (group *routerGroup) Handle(method, path string, handler fasthttp.RequestHandler, middlewares ...Middleware) *Route {
+	return group.root.register(method, group.path(path), handler, group.chain(middlewares))
+}
+
+func (group *routerGroup) Any(path string, handler fasthttp.RequestHandler, middlewares ...Middleware) *Route {
+	var route *Route
+	for _, method := range anyMethods {
+		route = group.Handle(method, path, handler, middlewares...)
+	}
+	return route
+}
+
+func (group *routerGroup) DELETE(path string, handler fasthttp.RequestHandler, middlewares ...Middleware) *Route {
+	return group.Handle("DELETE", path, handler, middlewares...)
 }
 
-func (group *routerGroup) GET(path string, handler fasthttp.RequestHandler) {
-	group.router.GET(fmt.Sprintf("%s%s", group.prefix, path), Middlewares(handler, group.middlewares...))
+func (group *routerGroup) GET(path string, handler fasthttp.RequestHandler, middlewares ...Middleware) *Route {
+	return group.Handle("GET", path, handler, middlewares...)
 }
 
-func (group *routerGroup) POST(path string, handler fasthttp.RequestHandler) {
-	group.router.POST(fmt.Sprintf("%s%s", group.prefix, path), Middlewares(handler, group.middlewares...))
+func (group *routerGroup) POST(path string, handler fasthttp.RequestHandler, middlewares ...Middleware) *Route {
+	return group.Handle("POST", path, handler, middlewares...)
 }
 
-func (group *routerGroup) PUT(path string, handler fasthttp.RequestHandler) {
-	group.router.PUT(fmt.Sprintf("%s%s", group.prefix, path), Middlewares(handler, group.middlewares...))
+func (group *routerGroup) PUT(path string, handler fasthttp.RequestHandler, middlewares ...Middleware) *Route {
+	return group.Handle("PUT", path, handler, middlewares...)
 }
 
-func (group *routerGroup) HEAD(path string, handler fasthttp.RequestHandler) {
-	group.router.HEAD(fmt.Sprintf("%s%s", group.prefix, path), Middlewares(handler, group.middlewares...))
+func (group *routerGroup) HEAD(path string, handler fasthttp.RequestHandler, middlewares ...Middleware) *Route {
+	return group.Handle("HEAD", path, handler, middlewares...)
 }
 
-func (group *routerGroup) OPTIONS(path string, handler fasthttp.RequestHandler) {
-	group.router.OPTIONS(fmt.Sprintf("%s%s", group.prefix, path), Middlewares(handler, group.middlewares...))
+func (group *routerGroup) OPTIONS(path string, handler fasthttp.RequestHandler, middlewares ...Middleware) *Route {
+	return group.Handle("OPTIONS", path, handler, middlewares...)
 }
 
-func (group *routerGroup) PATCH(path string, handler fasthttp.RequestHandler) {
-	group.router.PATCH(fmt.Sprintf("%s%s", group.prefix, path), Middlewares(handler, group.middlewares...))
+func (group *routerGroup) PATCH(path string, handler fasthttp.RequestHandler, middlewares ...Middleware) *Route {
+	return group.Handle("PATCH", path, handler, middlewares...)
 }
 
 func (group *routerGroup) Group(path string, middlewares ... Middleware) Routable {
 	return &routerGroup{
-		prefix:      path,
-		router:      group,
+		prefix:      group.root.groupPrefix(path),
+		root:        group.root,
+		parent:      group,
 		middlewares: middlewares,
 	}
 }
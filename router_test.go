@@ -18,6 +18,30 @@ func createRequestCtxFromPath(method, path string) *fasthttp.RequestCtx {
 var emptyHandler fasthttp.RequestHandler = func(ctx *fasthttp.RequestCtx) {
 }
 
+func routeHandler(ctx *fasthttp.RequestCtx) {
+}
+
+// traceMiddleware returns a middleware appending `name` to `calls` before
+// calling the next handler.
+func traceMiddleware(calls *[]string, name string) Middleware {
+	return func(next fasthttp.RequestHandler) fasthttp.RequestHandler {
+		return func(ctx *fasthttp.RequestCtx) {
+			*calls = append(*calls, name)
+			next(ctx)
+		}
+	}
+}
+
+// staticChild returns the static child of `n` with the given prefix.
+func staticChild(n *node, prefix string) *node {
+	for _, child := range n.children {
+		if string(child.prefix) == prefix {
+			return child
+		}
+	}
+	return nil
+}
+
 var _ = Describe("Router", func() {
 
 	Describe("Split", func() {
@@ -62,107 +86,144 @@ var _ = Describe("Router", func() {
 		})
 	})
 
+	Describe("CleanPath", func() {
+		It("should keep a clean path", func() {
+			Expect(CleanPath("/")).To(Equal("/"))
+			Expect(CleanPath("/a/b")).To(Equal("/a/b"))
+			Expect(CleanPath("/a/b/")).To(Equal("/a/b/"))
+		})
+
+		It("should clean the path", func() {
+			Expect(CleanPath("")).To(Equal("/"))
+			Expect(CleanPath("a/b")).To(Equal("/a/b"))
+			Expect(CleanPath("//a//b//")).To(Equal("/a/b/"))
+			Expect(CleanPath("/a/./b/../c")).To(Equal("/a/c"))
+			Expect(CleanPath("/../a")).To(Equal("/a"))
+			Expect(CleanPath("/a/..")).To(Equal("/"))
+		})
+
+		It("should report the paths to clean", func() {
+			for _, path := range []string{"/", "/a/b/", "/a/.b", "/a..b/c."} {
+				Expect(unclean([]byte(path))).To(BeFalse(), path)
+			}
+			for _, path := range []string{"", "a", "//a", "/a/./b", "/a/../b", "/a/.", "/a/.."} {
+				Expect(unclean([]byte(path))).To(BeTrue(), path)
+			}
+		})
+	})
+
 	Describe("Parse", func() {
 
 		It("should parse a GET", func() {
 			router := New()
 			router.GET("", emptyHandler)
 
-			Expect(router.children).To(HaveKey("GET"))
-			Expect(router.children["GET"].children).To(BeEmpty())
-			Expect(router.children["GET"].wildcard).To(BeNil())
-			Expect(router.children["GET"].handler).NotTo(BeNil())
+			Expect(router.tree("GET")).NotTo(BeNil())
+			Expect(router.tree("GET").children).To(BeEmpty())
+			Expect(router.tree("GET").wildcard).To(BeNil())
+			Expect(router.tree("GET").handler).NotTo(BeNil())
 		})
 
 		It("should parse a GET", func() {
 			router := New()
 			router.GET("/route", emptyHandler)
 
-			Expect(router.children).To(HaveKey("GET"))
-			Expect(router.children["GET"].children).To(HaveKey("route"))
-			Expect(router.children["GET"].wildcard).To(BeNil())
+			Expect(router.tree("GET")).NotTo(BeNil())
+			Expect(router.tree("GET").children).To(HaveLen(1))
+			Expect(staticChild(router.tree("GET"), "route")).NotTo(BeNil())
+			Expect(staticChild(router.tree("GET"), "route").handler).NotTo(BeNil())
+			Expect(router.tree("GET").wildcard).To(BeNil())
 		})
 
 		It("should parse a POST", func() {
 			router := New()
 			router.POST("/route", emptyHandler)
 
-			Expect(router.children).To(HaveKey("POST"))
-			Expect(router.children["POST"].children).To(HaveKey("route"))
-			Expect(router.children["POST"].wildcard).To(BeNil())
+			Expect(router.tree("POST")).NotTo(BeNil())
+			Expect(router.tree("POST").children).To(HaveLen(1))
+			Expect(staticChild(router.tree("POST"), "route")).NotTo(BeNil())
+			Expect(staticChild(router.tree("POST"), "route").handler).NotTo(BeNil())
+			Expect(router.tree("POST").wildcard).To(BeNil())
 		})
 
 		It("should parse a PUT", func() {
 			router := New()
 			router.PUT("/route", emptyHandler)
 
-			Expect(router.children).To(HaveKey("PUT"))
-			Expect(router.children["PUT"].children).To(HaveKey("route"))
-			Expect(router.children["PUT"].wildcard).To(BeNil())
+			Expect(router.tree("PUT")).NotTo(BeNil())
+			Expect(router.tree("PUT").children).To(HaveLen(1))
+			Expect(staticChild(router.tree("PUT"), "route")).NotTo(BeNil())
+			Expect(staticChild(router.tree("PUT"), "route").handler).NotTo(BeNil())
+			Expect(router.tree("PUT").wildcard).To(BeNil())
 		})
 
 		It("should parse a DELETE", func() {
 			router := New()
 			router.DELETE("/route", emptyHandler)
 
-			Expect(router.children).To(HaveKey("DELETE"))
-			Expect(router.children["DELETE"].children).To(HaveKey("route"))
-			Expect(router.children["DELETE"].wildcard).To(BeNil())
+			Expect(router.tree("DELETE")).NotTo(BeNil())
+			Expect(router.tree("DELETE").children).To(HaveLen(1))
+			Expect(staticChild(router.tree("DELETE"), "route")).NotTo(BeNil())
+			Expect(staticChild(router.tree("DELETE"), "route").handler).NotTo(BeNil())
+			Expect(router.tree("DELETE").wildcard).To(BeNil())
 		})
 
 		It("should parse a HEAD", func() {
 			router := New()
 			router.HEAD("/route", emptyHandler)
 
-			Expect(router.children).To(HaveKey("HEAD"))
-			Expect(router.children["HEAD"].children).To(HaveKey("route"))
-			Expect(router.children["HEAD"].wildcard).To(BeNil())
+			Expect(router.tree("HEAD")).NotTo(BeNil())
+			Expect(router.tree("HEAD").children).To(HaveLen(1))
+			Expect(staticChild(router.tree("HEAD"), "route")).NotTo(BeNil())
+			Expect(staticChild(router.tree("HEAD"), "route").handler).NotTo(BeNil())
+			Expect(router.tree("HEAD").wildcard).To(BeNil())
 		})
 
 		It("should parse a OPTIONS", func() {
 			router := New()
 			router.OPTIONS("/route", emptyHandler)
 
-			Expect(router.children).To(HaveKey("OPTIONS"))
-			Expect(router.children["OPTIONS"].children).To(HaveKey("route"))
-			Expect(router.children["OPTIONS"].wildcard).To(BeNil())
+			Expect(router.tree("OPTIONS")).NotTo(BeNil())
+			Expect(router.tree("OPTIONS").children).To(HaveLen(1))
+			Expect(staticChild(router.tree("OPTIONS"), "route")).NotTo(BeNil())
+			Expect(staticChild(router.tree("OPTIONS"), "route").handler).NotTo(BeNil())
+			Expect(router.tree("OPTIONS").wildcard).To(BeNil())
 		})
 
 		It("should parse a PATCH", func() {
 			router := New()
 			router.PATCH("/route", emptyHandler)
 
-			Expect(router.children).To(HaveKey("PATCH"))
-			Expect(router.children["PATCH"].children).To(HaveKey("route"))
-			Expect(router.children["PATCH"].wildcard).To(BeNil())
+			Expect(router.tree("PATCH")).NotTo(BeNil())
+			Expect(router.tree("PATCH").children).To(HaveLen(1))
+			Expect(staticChild(router.tree("PATCH"), "route")).NotTo(BeNil())
+			Expect(staticChild(router.tree("PATCH"), "route").handler).NotTo(BeNil())
+			Expect(router.tree("PATCH").wildcard).To(BeNil())
 		})
 
 		It("should parse a POST", func() {
 			router := New()
 			router.POST("/route", emptyHandler)
 
-			Expect(router.children).To(HaveKey("POST"))
-			Expect(router.children["POST"].children).To(HaveKey("route"))
-			Expect(router.children["POST"].wildcard).To(BeNil())
+			Expect(router.tree("POST")).NotTo(BeNil())
+			Expect(router.tree("POST").children).To(HaveLen(1))
+			Expect(staticChild(router.tree("POST"), "route")).NotTo(BeNil())
+			Expect(staticChild(router.tree("POST"), "route").handler).NotTo(BeNil())
+			Expect(router.tree("POST").wildcard).To(BeNil())
 		})
 
 		It("should parse a complete static route", func() {
 			router := New()
 			router.GET("/this/should/be/static", emptyHandler)
 
-			Expect(router.children).To(HaveKey("GET"))
-			Expect(router.children["GET"].children).To(HaveKey("this"))
-			Expect(router.children["GET"].wildcard).To(BeNil())
-			Expect(router.children["GET"].children["this"].wildcard).To(BeNil())
-			Expect(router.children["GET"].children["this"].handler).To(BeNil())
-			Expect(router.children["GET"].children["this"].children).To(HaveKey("should"))
-			Expect(router.children["GET"].children["this"].children["should"].wildcard).To(BeNil())
-			Expect(router.children["GET"].children["this"].children["should"].handler).To(BeNil())
-			Expect(router.children["GET"].children["this"].children["should"].children).To(HaveKey("be"))
-			Expect(router.children["GET"].children["this"].children["should"].children["be"].wildcard).To(BeNil())
-			Expect(router.children["GET"].children["this"].children["should"].children["be"].handler).To(BeNil())
-			Expect(router.children["GET"].children["this"].children["should"].children["be"].children).To(HaveKey("static"))
-			Expect(fmt.Sprintf("%p", router.children["GET"].children["this"].children["should"].children["be"].children["static"].handler)).To(Equal(fmt.Sprintf("%p", emptyHandler)))
+			Expect(router.tree("GET")).NotTo(BeNil())
+			Expect(router.tree("GET").wildcard).To(BeNil())
+			Expect(router.tree("GET").handler).To(BeNil())
+			Expect(router.tree("GET").children).To(HaveLen(1))
+			Expect(staticChild(router.tree("GET"), "this/should/be/static")).NotTo(BeNil())
+			Expect(staticChild(router.tree("GET"), "this/should/be/static").wildcard).To(BeNil())
+			Expect(staticChild(router.tree("GET"), "this/should/be/static").children).To(BeEmpty())
+			Expect(fmt.Sprintf("%p", staticChild(router.tree("GET"), "this/should/be/static").handler)).To(Equal(fmt.Sprintf("%p", emptyHandler)))
 		})
 
 		It("should parse multiple static routes related", func() {
@@ -170,45 +231,41 @@ var _ = Describe("Router", func() {
 			router.GET("/this/should/be/static", emptyHandler)
 			router.GET("/this/should2/be/static", emptyHandler)
 
-			Expect(router.children).To(HaveKey("GET"))
-			Expect(router.children["GET"].children).To(HaveKey("this"))
-			Expect(router.children["GET"].wildcard).To(BeNil())
-			Expect(router.children["GET"].children["this"].wildcard).To(BeNil())
-			Expect(router.children["GET"].children["this"].handler).To(BeNil())
-
-			Expect(router.children["GET"].children["this"].children).To(HaveKey("should"))
-			Expect(router.children["GET"].children["this"].children["should"].wildcard).To(BeNil())
-			Expect(router.children["GET"].children["this"].children["should"].handler).To(BeNil())
-			Expect(router.children["GET"].children["this"].children["should"].children).To(HaveKey("be"))
-			Expect(router.children["GET"].children["this"].children["should"].children["be"].wildcard).To(BeNil())
-			Expect(router.children["GET"].children["this"].children["should"].children["be"].handler).To(BeNil())
-			Expect(router.children["GET"].children["this"].children["should"].children["be"].children).To(HaveKey("static"))
-			Expect(fmt.Sprintf("%p", router.children["GET"].children["this"].children["should"].children["be"].children["static"].handler)).To(Equal(fmt.Sprintf("%p", emptyHandler)))
-
-			Expect(router.children["GET"].children["this"].children).To(HaveKey("should2"))
-			Expect(router.children["GET"].children["this"].children["should2"].wildcard).To(BeNil())
-			Expect(router.children["GET"].children["this"].children["should2"].handler).To(BeNil())
-			Expect(router.children["GET"].children["this"].children["should2"].children).To(HaveKey("be"))
-			Expect(router.children["GET"].children["this"].children["should2"].children["be"].wildcard).To(BeNil())
-			Expect(router.children["GET"].children["this"].children["should2"].children["be"].handler).To(BeNil())
-			Expect(router.children["GET"].children["this"].children["should2"].children["be"].children).To(HaveKey("static"))
-			Expect(fmt.Sprintf("%p", router.children["GET"].children["this"].children["should2"].children["be"].children["static"].handler)).To(Equal(fmt.Sprintf("%p", emptyHandler)))
+			Expect(router.tree("GET")).NotTo(BeNil())
+			Expect(router.tree("GET").wildcard).To(BeNil())
+			Expect(router.tree("GET").children).To(HaveLen(1))
+			this := staticChild(router.tree("GET"), "this/should")
+			Expect(this).NotTo(BeNil())
+			Expect(this.wildcard).To(BeNil())
+			Expect(this.handler).To(BeNil())
+			Expect(this.children).To(HaveLen(2))
+			Expect(this.indices).To(Equal([]byte("/2")))
+
+			Expect(staticChild(this, "/be/static")).NotTo(BeNil())
+			Expect(staticChild(this, "/be/static").wildcard).To(BeNil())
+			Expect(staticChild(this, "/be/static").children).To(BeEmpty())
+			Expect(fmt.Sprintf("%p", staticChild(this, "/be/static").handler)).To(Equal(fmt.Sprintf("%p", emptyHandler)))
+
+			Expect(staticChild(this, "2/be/static")).NotTo(BeNil())
+			Expect(staticChild(this, "2/be/static").wildcard).To(BeNil())
+			Expect(staticChild(this, "2/be/static").children).To(BeEmpty())
+			Expect(fmt.Sprintf("%p", staticChild(this, "2/be/static").handler)).To(Equal(fmt.Sprintf("%p", emptyHandler)))
 		})
 
 		It("should parse a complete a route starting static and ending with a wildcard", func() {
 			router := New()
 			router.GET("/static/:wildcard", emptyHandler)
 
-			Expect(router.children).To(HaveKey("GET"))
-			Expect(router.children["GET"].wildcard).To(BeNil())
-			Expect(router.children["GET"].names).To(BeEmpty())
-			Expect(router.children["GET"].children).To(HaveKey("static"))
-			Expect(router.children["GET"].children["static"].children).To(BeEmpty())
-			Expect(router.children["GET"].children["static"].handler).To(BeNil())
-			Expect(router.children["GET"].children["static"].wildcard).NotTo(BeNil())
-			Expect(router.children["GET"].children["static"].wildcard.handler).NotTo(BeNil())
-			Expect(router.children["GET"].children["static"].wildcard.children).To(BeEmpty())
-			Expect(router.children["GET"].children["static"].wildcard.names).To(Equal([]string{"wildcard"}))
+			Expect(router.tree("GET")).NotTo(BeNil())
+			Expect(router.tree("GET").wildcard).To(BeNil())
+			Expect(router.tree("GET").names).To(BeEmpty())
+			Expect(staticChild(router.tree("GET"), "static/")).NotTo(BeNil())
+			Expect(staticChild(router.tree("GET"), "static/").children).To(BeEmpty())
+			Expect(staticChild(router.tree("GET"), "static/").handler).To(BeNil())
+			Expect(staticChild(router.tree("GET"), "static/").wildcard).NotTo(BeNil())
+			Expect(staticChild(router.tree("GET"), "static/").wildcard.handler).NotTo(BeNil())
+			Expect(staticChild(router.tree("GET"), "static/").wildcard.children).To(BeEmpty())
+			Expect(staticChild(router.tree("GET"), "static/").wildcard.names).To(Equal([]string{"wildcard"}))
 		})
 
 		It("should parse multiple static routes related and not", func() {
@@ -217,77 +274,68 @@ var _ = Describe("Router", func() {
 			router.GET("/this/should2/be/static", emptyHandler)
 			router.GET("/this2/should/be/static", emptyHandler)
 
-			Expect(router.children).To(HaveKey("GET"))
-			Expect(router.children["GET"].children).To(HaveKey("this"))
-			Expect(router.children["GET"].wildcard).To(BeNil())
-			Expect(router.children["GET"].children["this"].wildcard).To(BeNil())
-			Expect(router.children["GET"].children["this"].handler).To(BeNil())
-			Expect(router.children["GET"].children["this"].children).To(HaveKey("should"))
-			Expect(router.children["GET"].children["this"].children["should"].wildcard).To(BeNil())
-			Expect(router.children["GET"].children["this"].children["should"].handler).To(BeNil())
-			Expect(router.children["GET"].children["this"].children["should"].children).To(HaveKey("be"))
-			Expect(router.children["GET"].children["this"].children["should"].children["be"].wildcard).To(BeNil())
-			Expect(router.children["GET"].children["this"].children["should"].children["be"].handler).To(BeNil())
-			Expect(router.children["GET"].children["this"].children["should"].children["be"].children).To(HaveKey("static"))
-			Expect(fmt.Sprintf("%p", router.children["GET"].children["this"].children["should"].children["be"].children["static"].handler)).To(Equal(fmt.Sprintf("%p", emptyHandler)))
-
-			Expect(router.children["GET"].children["this"].children).To(HaveKey("should2"))
-			Expect(router.children["GET"].children["this"].children["should2"].wildcard).To(BeNil())
-			Expect(router.children["GET"].children["this"].children["should2"].handler).To(BeNil())
-			Expect(router.children["GET"].children["this"].children["should2"].children).To(HaveKey("be"))
-			Expect(router.children["GET"].children["this"].children["should2"].children["be"].wildcard).To(BeNil())
-			Expect(router.children["GET"].children["this"].children["should2"].children["be"].handler).To(BeNil())
-			Expect(router.children["GET"].children["this"].children["should2"].children["be"].children).To(HaveKey("static"))
-			Expect(fmt.Sprintf("%p", router.children["GET"].children["this"].children["should2"].children["be"].children["static"].handler)).To(Equal(fmt.Sprintf("%p", emptyHandler)))
-
-			Expect(router.children["GET"].children).To(HaveKey("this2"))
-			Expect(router.children["GET"].wildcard).To(BeNil())
-			Expect(router.children["GET"].children["this2"].wildcard).To(BeNil())
-			Expect(router.children["GET"].children["this2"].handler).To(BeNil())
-			Expect(router.children["GET"].children["this2"].children).To(HaveKey("should"))
-			Expect(router.children["GET"].children["this2"].children["should"].wildcard).To(BeNil())
-			Expect(router.children["GET"].children["this2"].children["should"].handler).To(BeNil())
-			Expect(router.children["GET"].children["this2"].children["should"].children).To(HaveKey("be"))
-			Expect(router.children["GET"].children["this2"].children["should"].children["be"].wildcard).To(BeNil())
-			Expect(router.children["GET"].children["this2"].children["should"].children["be"].handler).To(BeNil())
-			Expect(router.children["GET"].children["this2"].children["should"].children["be"].children).To(HaveKey("static"))
-			Expect(fmt.Sprintf("%p", router.children["GET"].children["this2"].children["should"].children["be"].children["static"].handler)).To(Equal(fmt.Sprintf("%p", emptyHandler)))
+			Expect(router.tree("GET")).NotTo(BeNil())
+			Expect(router.tree("GET").wildcard).To(BeNil())
+			Expect(router.tree("GET").children).To(HaveLen(1))
+			this := staticChild(router.tree("GET"), "this")
+			Expect(this).NotTo(BeNil())
+			Expect(this.handler).To(BeNil())
+			Expect(this.wildcard).To(BeNil())
+			Expect(this.children).To(HaveLen(2))
+
+			Expect(staticChild(this, "/should")).NotTo(BeNil())
+			Expect(staticChild(this, "/should").handler).To(BeNil())
+			Expect(staticChild(this, "/should").children).To(HaveLen(2))
+			Expect(staticChild(staticChild(this, "/should"), "/be/static")).NotTo(BeNil())
+			Expect(fmt.Sprintf("%p", staticChild(staticChild(this, "/should"), "/be/static").handler)).To(Equal(fmt.Sprintf("%p", emptyHandler)))
+			Expect(staticChild(staticChild(this, "/should"), "2/be/static")).NotTo(BeNil())
+			Expect(fmt.Sprintf("%p", staticChild(staticChild(this, "/should"), "2/be/static").handler)).To(Equal(fmt.Sprintf("%p", emptyHandler)))
+
+			Expect(staticChild(this, "2/should/be/static")).NotTo(BeNil())
+			Expect(staticChild(this, "2/should/be/static").wildcard).To(BeNil())
+			Expect(staticChild(this, "2/should/be/static").children).To(BeEmpty())
+			Expect(fmt.Sprintf("%p", staticChild(this, "2/should/be/static").handler)).To(Equal(fmt.Sprintf("%p", emptyHandler)))
 		})
 
 		It("should parse a complete route with wildcard", func() {
 			router := New()
 			router.GET("/:account/detail/another", emptyHandler)
 
-			Expect(router.children).To(HaveKey("GET"))
-			Expect(router.children["GET"].children).To(BeEmpty())
-			Expect(router.children["GET"].wildcard).NotTo(BeNil())
-			Expect(router.children["GET"].wildcard.children).To(HaveKey("detail"))
-			Expect(router.children["GET"].wildcard.handler).To(BeNil())
-			Expect(router.children["GET"].wildcard.wildcard).To(BeNil())
-			Expect(router.children["GET"].wildcard.children["detail"].children).To(HaveKey("another"))
-			Expect(router.children["GET"].wildcard.children["detail"].handler).To(BeNil())
-			Expect(router.children["GET"].wildcard.children["detail"].wildcard).To(BeNil())
-			Expect(router.children["GET"].wildcard.children["detail"].children["another"].wildcard).To(BeNil())
-			Expect(router.children["GET"].wildcard.children["detail"].children["another"].handler).NotTo(BeNil())
-			Expect(router.children["GET"].wildcard.children["detail"].children["another"].names).To(Equal([]string{"account"}))
+			Expect(router.tree("GET")).NotTo(BeNil())
+			Expect(router.tree("GET").children).To(BeEmpty())
+			Expect(router.tree("GET").wildcard).NotTo(BeNil())
+			Expect(router.tree("GET").wildcard.handler).To(BeNil())
+			Expect(router.tree("GET").wildcard.wildcard).To(BeNil())
+			Expect(router.tree("GET").wildcard.children).To(HaveLen(1))
+			Expect(staticChild(router.tree("GET").wildcard, "/detail/another")).NotTo(BeNil())
+			Expect(staticChild(router.tree("GET").wildcard, "/detail/another").wildcard).To(BeNil())
+			Expect(staticChild(router.tree("GET").wildcard, "/detail/another").handler).NotTo(BeNil())
+			Expect(staticChild(router.tree("GET").wildcard, "/detail/another").names).To(Equal([]string{"account"}))
 		})
 
 		It("should parse a complete route with a sequence of wildcards", func() {
 			router := New()
 			router.GET("/:account/:transaction/:invoice", emptyHandler)
 
-			Expect(router.children).To(HaveKey("GET"))
-			Expect(router.children["GET"].children).To(BeEmpty())
-			Expect(router.children["GET"].wildcard).NotTo(BeNil())
-			Expect(router.children["GET"].wildcard.handler).To(BeNil())
-			Expect(router.children["GET"].wildcard.names).To(BeEmpty())
-			Expect(router.children["GET"].wildcard.wildcard).NotTo(BeNil())
-			Expect(router.children["GET"].wildcard.wildcard.handler).To(BeNil())
-			Expect(router.children["GET"].wildcard.wildcard.names).To(BeEmpty())
-			Expect(router.children["GET"].wildcard.wildcard.wildcard).NotTo(BeNil())
-			Expect(router.children["GET"].wildcard.wildcard.wildcard.wildcard).To(BeNil())
-			Expect(router.children["GET"].wildcard.wildcard.wildcard.handler).NotTo(BeNil())
-			Expect(router.children["GET"].wildcard.wildcard.wildcard.names).To(Equal([]string{"account", "transaction", "invoice"}))
+			Expect(router.tree("GET")).NotTo(BeNil())
+			Expect(router.tree("GET").children).To(BeEmpty())
+			account := router.tree("GET").wildcard
+			Expect(account).NotTo(BeNil())
+			Expect(account.handler).To(BeNil())
+			Expect(account.names).To(BeEmpty())
+			Expect(account.wildcard).To(BeNil())
+			Expect(staticChild(account, "/")).NotTo(BeNil())
+			transaction := staticChild(account, "/").wildcard
+			Expect(transaction).NotTo(BeNil())
+			Expect(transaction.handler).To(BeNil())
+			Expect(transaction.names).To(BeEmpty())
+			Expect(staticChild(transaction, "/")).NotTo(BeNil())
+			invoice := staticChild(transaction, "/").wildcard
+			Expect(invoice).NotTo(BeNil())
+			Expect(invoice.wildcard).To(BeNil())
+			Expect(invoice.children).To(BeEmpty())
+			Expect(invoice.handler).NotTo(BeNil())
+			Expect(invoice.names).To(Equal([]string{"account", "transaction", "invoice"}))
 		})
 
 		It("should parse multiple routes starting with wildcards", func() {
@@ -296,18 +344,21 @@ var _ = Describe("Router", func() {
 			router.GET("/:account/history", emptyHandler)
 			router.GET("/:transaction/invoice", emptyHandler)
 
-			Expect(router.children).To(HaveKey("GET"))
-			Expect(router.children["GET"].children).To(BeEmpty())
-			Expect(router.children["GET"].wildcard).NotTo(BeNil())
-			Expect(router.children["GET"].wildcard.children).To(HaveKey("detail"))
-			Expect(router.children["GET"].wildcard.handler).To(BeNil())
-			Expect(router.children["GET"].wildcard.wildcard).To(BeNil())
-			Expect(router.children["GET"].wildcard.children["detail"].children).To(BeEmpty())
-			Expect(router.children["GET"].wildcard.children["detail"].handler).NotTo(BeNil())
-			Expect(router.children["GET"].wildcard.children["detail"].wildcard).To(BeNil())
-			Expect(router.children["GET"].wildcard.children["detail"].names).To(Equal([]string{"account"}))
-			Expect(router.children["GET"].wildcard.children["history"].names).To(Equal([]string{"account"}))
-			Expect(router.children["GET"].wildcard.children["invoice"].names).To(Equal([]string{"transaction"}))
+			Expect(router.tree("GET")).NotTo(BeNil())
+			Expect(router.tree("GET").children).To(BeEmpty())
+			Expect(router.tree("GET").wildcard).NotTo(BeNil())
+			Expect(router.tree("GET").wildcard.handler).To(BeNil())
+			Expect(router.tree("GET").wildcard.wildcard).To(BeNil())
+			slash := staticChild(router.tree("GET").wildcard, "/")
+			Expect(slash).NotTo(BeNil())
+			Expect(slash.handler).To(BeNil())
+			Expect(slash.children).To(HaveLen(3))
+			Expect(staticChild(slash, "detail").children).To(BeEmpty())
+			Expect(staticChild(slash, "detail").handler).NotTo(BeNil())
+			Expect(staticChild(slash, "detail").wildcard).To(BeNil())
+			Expect(staticChild(slash, "detail").names).To(Equal([]string{"account"}))
+			Expect(staticChild(slash, "history").names).To(Equal([]string{"account"}))
+			Expect(staticChild(slash, "invoice").names).To(Equal([]string{"transaction"}))
 		})
 
 		It("should parse multiple mixed routes", func() {
@@ -316,30 +367,427 @@ var _ = Describe("Router", func() {
 			router.GET("/accounts/:account/history", emptyHandler)
 			router.GET("/:transaction/invoice", emptyHandler)
 
-			Expect(router.children).To(HaveKey("GET"))
-			Expect(router.children["GET"].children).To(HaveLen(1))
-			Expect(router.children["GET"].children).To(HaveKey("accounts"))
-			Expect(router.children["GET"].children["accounts"].children).To(BeEmpty())
-			Expect(router.children["GET"].children["accounts"].wildcard).NotTo(BeNil())
-			Expect(router.children["GET"].children["accounts"].handler).To(BeNil())
-			Expect(router.children["GET"].children["accounts"].wildcard.children).To(HaveLen(2))
-			Expect(router.children["GET"].children["accounts"].wildcard.children).To(HaveKey("detail"))
-			Expect(router.children["GET"].children["accounts"].wildcard.children["detail"].wildcard).To(BeNil())
-			Expect(router.children["GET"].children["accounts"].wildcard.children["detail"].children).To(BeEmpty())
-			Expect(router.children["GET"].children["accounts"].wildcard.children["detail"].handler).NotTo(BeNil())
-			Expect(router.children["GET"].children["accounts"].wildcard.children["detail"].names).To(Equal([]string{"account"}))
-			Expect(router.children["GET"].children["accounts"].wildcard.children).To(HaveKey("history"))
-			Expect(router.children["GET"].children["accounts"].wildcard.children["history"].wildcard).To(BeNil())
-			Expect(router.children["GET"].children["accounts"].wildcard.children["history"].children).To(BeEmpty())
-			Expect(router.children["GET"].children["accounts"].wildcard.children["history"].handler).NotTo(BeNil())
-			Expect(router.children["GET"].children["accounts"].wildcard.children["history"].names).To(Equal([]string{"account"}))
-			Expect(router.children["GET"].wildcard).NotTo(BeNil())
-			Expect(router.children["GET"].wildcard.handler).To(BeNil())
-			Expect(router.children["GET"].wildcard.children).To(HaveKey("invoice"))
-			Expect(router.children["GET"].wildcard.children["invoice"].wildcard).To(BeNil())
-			Expect(router.children["GET"].wildcard.children["invoice"].children).To(BeEmpty())
-			Expect(router.children["GET"].wildcard.children["invoice"].handler).NotTo(BeNil())
-			Expect(router.children["GET"].wildcard.children["invoice"].names).To(Equal([]string{"transaction"}))
+			Expect(router.tree("GET")).NotTo(BeNil())
+			Expect(router.tree("GET").children).To(HaveLen(1))
+			accounts := staticChild(router.tree("GET"), "accounts/")
+			Expect(accounts).NotTo(BeNil())
+			Expect(accounts.children).To(BeEmpty())
+			Expect(accounts.wildcard).NotTo(BeNil())
+			Expect(accounts.handler).To(BeNil())
+			slash := staticChild(accounts.wildcard, "/")
+			Expect(slash).NotTo(BeNil())
+			Expect(slash.children).To(HaveLen(2))
+			Expect(staticChild(slash, "detail")).NotTo(BeNil())
+			Expect(staticChild(slash, "detail").wildcard).To(BeNil())
+			Expect(staticChild(slash, "detail").children).To(BeEmpty())
+			Expect(staticChild(slash, "detail").handler).NotTo(BeNil())
+			Expect(staticChild(slash, "detail").names).To(Equal([]string{"account"}))
+			Expect(staticChild(slash, "history")).NotTo(BeNil())
+			Expect(staticChild(slash, "history").wildcard).To(BeNil())
+			Expect(staticChild(slash, "history").children).To(BeEmpty())
+			Expect(staticChild(slash, "history").handler).NotTo(BeNil())
+			Expect(staticChild(slash, "history").names).To(Equal([]string{"account"}))
+			Expect(router.tree("GET").wildcard).NotTo(BeNil())
+			Expect(router.tree("GET").wildcard.handler).To(BeNil())
+			Expect(staticChild(router.tree("GET").wildcard, "/invoice")).NotTo(BeNil())
+			Expect(staticChild(router.tree("GET").wildcard, "/invoice").wildcard).To(BeNil())
+			Expect(staticChild(router.tree("GET").wildcard, "/invoice").children).To(BeEmpty())
+			Expect(staticChild(router.tree("GET").wildcard, "/invoice").handler).NotTo(BeNil())
+			Expect(staticChild(router.tree("GET").wildcard, "/invoice").names).To(Equal([]string{"transaction"}))
+		})
+
+		It("should split the static prefix of existing routes", func() {
+			router := New()
+			router.GET("/users/new", emptyHandler)
+			router.GET("/users", emptyHandler)
+			router.GET("/users/:id", emptyHandler)
+
+			users := staticChild(router.tree("GET"), "users")
+			Expect(users).NotTo(BeNil())
+			Expect(users.handler).NotTo(BeNil())
+			Expect(users.names).To(BeEmpty())
+			Expect(staticChild(users, "/")).NotTo(BeNil())
+			Expect(staticChild(users, "/").handler).To(BeNil())
+			Expect(staticChild(staticChild(users, "/"), "new")).NotTo(BeNil())
+			Expect(staticChild(staticChild(users, "/"), "new").handler).NotTo(BeNil())
+			Expect(staticChild(users, "/").wildcard).NotTo(BeNil())
+			Expect(staticChild(users, "/").wildcard.names).To(Equal([]string{"id"}))
+		})
+
+		It("should parse a route ending with a catch-all", func() {
+			router := New()
+			router.GET("/static/*filepath", emptyHandler)
+
+			Expect(router.tree("GET")).NotTo(BeNil())
+			static := staticChild(router.tree("GET"), "static/")
+			Expect(static).NotTo(BeNil())
+			Expect(static.handler).To(BeNil())
+			Expect(static.wildcard).To(BeNil())
+			Expect(static.catchAll).NotTo(BeNil())
+			Expect(static.catchAll.handler).NotTo(BeNil())
+			Expect(static.catchAll.names).To(Equal([]string{"filepath"}))
+		})
+
+		It("should parse a catch-all after wildcards", func() {
+			router := New()
+			router.GET("/:account/files/*filepath", emptyHandler)
+
+			Expect(router.tree("GET").wildcard).NotTo(BeNil())
+			files := staticChild(router.tree("GET").wildcard, "/files/")
+			Expect(files).NotTo(BeNil())
+			Expect(files.catchAll).NotTo(BeNil())
+			Expect(files.catchAll.names).To(Equal([]string{"account", "filepath"}))
+		})
+
+		It("should panic due to a catch-all not being the last segment", func() {
+			router := New()
+			Expect(func() {
+				router.GET("/static/*filepath/detail", emptyHandler)
+			}).To(Panic())
+			Expect(func() {
+				router.GET("/static/*filepath/", emptyHandler)
+			}).To(Panic())
+		})
+
+		It("should panic due to conflicting catch-all routes", func() {
+			router := New()
+			router.GET("/static/*filepath", emptyHandler)
+			Expect(func() {
+				router.GET("/static/*path", emptyHandler)
+			}).To(Panic())
+		})
+
+		It("should panic due to a catch-all conflicting with a wildcard", func() {
+			router := New()
+			router.GET("/static/:file", emptyHandler)
+			Expect(func() {
+				router.GET("/static/*filepath", emptyHandler)
+			}).To(Panic())
+
+			router.GET("/files/*filepath", emptyHandler)
+			Expect(func() {
+				router.GET("/files/:file/detail", emptyHandler)
+			}).To(Panic())
+		})
+
+		It("should not panic with a catch-all next to static routes", func() {
+			router := New()
+			Expect(func() {
+				router.GET("/static", emptyHandler)
+				router.GET("/static/index.html", emptyHandler)
+				router.GET("/static/*filepath", emptyHandler)
+			}).NotTo(Panic())
+		})
+
+		It("should parse constrained wildcards in registration order", func() {
+			router := New()
+			router.GET("/users/:id{[0-9]+}", emptyHandler)
+			router.GET("/users/:slug{[a-z-]+}/posts", emptyHandler)
+			router.GET("/users/:id{[0-9]+}/posts", emptyHandler)
+			router.GET("/users/:name", emptyHandler)
+
+			users := staticChild(router.tree("GET"), "users/")
+			Expect(users).NotTo(BeNil())
+			Expect(users.constrained).To(HaveLen(2))
+			Expect(users.constrained[0].constraint).To(Equal("{[0-9]+}"))
+			Expect(users.constrained[0].handler).NotTo(BeNil())
+			Expect(users.constrained[0].names).To(Equal([]string{"id"}))
+			Expect(staticChild(users.constrained[0], "/posts")).NotTo(BeNil())
+			Expect(users.constrained[1].constraint).To(Equal("{[a-z-]+}"))
+			Expect(users.constrained[1].handler).To(BeNil())
+			Expect(staticChild(users.constrained[1], "/posts").names).To(Equal([]string{"slug"}))
+			Expect(users.wildcard).NotTo(BeNil())
+			Expect(users.wildcard.names).To(Equal([]string{"name"}))
+		})
+
+		It("should parse a constraint with braces", func() {
+			router := New()
+			router.GET("/years/:year{[0-9]{4}}", emptyHandler)
+
+			years := staticChild(router.tree("GET"), "years/")
+			Expect(years.constrained).To(HaveLen(1))
+			Expect(years.constrained[0].constraint).To(Equal("{[0-9]{4}}"))
+			Expect(years.constrained[0].names).To(Equal([]string{"year"}))
+		})
+
+		It("should panic due to an invalid constraint", func() {
+			router := New()
+			Expect(func() {
+				router.GET("/users/:id{[0-9+}", emptyHandler)
+			}).To(Panic())
+			Expect(func() {
+				router.GET("/users/:id{[0-9]+", emptyHandler)
+			}).To(Panic())
+			Expect(func() {
+				router.GET("/users/:id{}", emptyHandler)
+			}).To(Panic())
+		})
+
+		It("should panic due to conflicting constrained routes", func() {
+			router := New()
+			router.GET("/users/:id{[0-9]+}", emptyHandler)
+			Expect(func() {
+				router.GET("/users/:user{[0-9]+}", emptyHandler)
+			}).To(Panic())
+			Expect(func() {
+				router.GET("/users/*path", emptyHandler)
+			}).To(Panic())
+		})
+
+		It("should parse typed wildcards next to the wildcard", func() {
+			router := New()
+			router.GET("/files/:id<int>", emptyHandler)
+			router.GET("/files/:ref<uuid>", emptyHandler)
+			router.GET("/files/:name", emptyHandler)
+
+			files := staticChild(router.tree("GET"), "files/")
+			Expect(files.constrained).To(HaveLen(2))
+			Expect(files.constrained[0].constraint).To(Equal("<int>"))
+			Expect(files.constrained[0].names).To(Equal([]string{"id"}))
+			Expect(files.constrained[1].constraint).To(Equal("<uuid>"))
+			Expect(files.constrained[1].names).To(Equal([]string{"ref"}))
+			Expect(files.wildcard.names).To(Equal([]string{"name"}))
+		})
+
+		It("should panic due to an unknown parameter type", func() {
+			router := New()
+			Expect(func() {
+				router.GET("/files/:id<hex>", emptyHandler)
+			}).To(Panic())
+			Expect(func() {
+				router.GET("/files/:id<int", emptyHandler)
+			}).To(Panic())
+		})
+
+		It("should panic due to conflicting typed routes", func() {
+			router := New()
+			router.GET("/files/:id<int>", emptyHandler)
+			Expect(func() {
+				router.GET("/files/:file<int>", emptyHandler)
+			}).To(Panic())
+		})
+
+		It("should parse wildcards in the middle of a segment", func() {
+			router := New()
+			router.GET("/files/:name.:ext", emptyHandler)
+			router.GET("/reports/report-:year<int>.csv", emptyHandler)
+			router.GET("/api/v:major/users", emptyHandler)
+
+			files := staticChild(router.tree("GET"), "files/")
+			Expect(files.wildcard).NotTo(BeNil())
+			Expect(files.wildcard.inSegment).To(BeTrue())
+			dot := staticChild(files.wildcard, ".")
+			Expect(dot).NotTo(BeNil())
+			Expect(dot.wildcard.inSegment).To(BeFalse())
+			Expect(dot.wildcard.names).To(Equal([]string{"name", "ext"}))
+
+			reports := staticChild(router.tree("GET"), "reports/report-")
+			Expect(reports.constrained).To(HaveLen(1))
+			Expect(reports.constrained[0].inSegment).To(BeTrue())
+			Expect(staticChild(reports.constrained[0], ".csv").names).To(Equal([]string{"year"}))
+
+			api := staticChild(router.tree("GET"), "api/v")
+			Expect(api.wildcard.inSegment).To(BeFalse())
+			Expect(staticChild(api.wildcard, "/users").names).To(Equal([]string{"major"}))
+		})
+
+		It("should end the parameter names at non name characters", func() {
+			router := New()
+			router.GET("/users/:user_id-:version2", emptyHandler)
+
+			users := staticChild(router.tree("GET"), "users/")
+			Expect(staticChild(users.wildcard, "-").wildcard.names).To(Equal([]string{"user_id", "version2"}))
+		})
+
+		It("should panic due to ambiguous parameters", func() {
+			router := New()
+			Expect(func() {
+				router.GET("/files/:name:ext", emptyHandler)
+			}).To(Panic())
+			Expect(func() {
+				router.GET("/files/:id<int>:ext", emptyHandler)
+			}).To(Panic())
+			Expect(func() {
+				router.GET("/files/name.:", emptyHandler)
+			}).To(Panic())
+		})
+
+		It("should panic due to conflicting mid-segment routes", func() {
+			router := New()
+			router.GET("/files/:name.json", emptyHandler)
+			Expect(func() {
+				router.GET("/files/:file.json", emptyHandler)
+			}).To(Panic())
+			Expect(func() {
+				router.GET("/files/report.json", emptyHandler)
+			}).NotTo(Panic())
+		})
+
+		It("should parse an optional trailing parameter", func() {
+			router := New()
+			router.GET("/posts/:page?", emptyHandler)
+
+			posts := staticChild(router.tree("GET"), "posts")
+			Expect(posts).NotTo(BeNil())
+			Expect(posts.handler).NotTo(BeNil())
+			Expect(posts.names).To(BeEmpty())
+			Expect(staticChild(posts, "/").wildcard.handler).NotTo(BeNil())
+			Expect(staticChild(posts, "/").wildcard.names).To(Equal([]string{"page"}))
+		})
+
+		It("should parse an optional segment", func() {
+			router := New()
+			router.GET("/docs/(:lang)/intro", emptyHandler)
+
+			docs := staticChild(router.tree("GET"), "docs/")
+			Expect(docs).NotTo(BeNil())
+			Expect(staticChild(docs, "intro").handler).NotTo(BeNil())
+			Expect(staticChild(docs.wildcard, "/intro").names).To(Equal([]string{"lang"}))
+		})
+
+		It("should expand every combination of optional segments", func() {
+			Expect(expandOptional("posts/:page?")).To(Equal([]string{"posts/:page", "posts"}))
+			Expect(expandOptional("(:lang)/docs/:page?/")).To(Equal([]string{
+				":lang/docs/:page/", "docs/:page/", ":lang/docs/", "docs/",
+			}))
+			Expect(expandOptional("(v:major)")).To(Equal([]string{"v:major", ""}))
+			Expect(expandOptional("users/:id{[0-9]?}")).To(Equal([]string{"users/:id{[0-9]?}"}))
+		})
+
+		It("should panic due to a conflicting optional variant", func() {
+			router := New()
+			router.GET("/posts", emptyHandler)
+			Expect(func() {
+				router.GET("/posts/:page?", emptyHandler)
+			}).To(Panic())
+
+			router.GET("/docs/:lang/intro", emptyHandler)
+			Expect(func() {
+				router.GET("/docs/(:version)/intro", emptyHandler)
+			}).To(Panic())
+		})
+
+		It("should return the conflicting routes", func() {
+			router := New()
+			router.GET("/users/:id", emptyHandler)
+			router.GET("/files/*filepath", emptyHandler)
+
+			err := router.TryHandle("GET", "/users/:name", emptyHandler)
+			Expect(err).To(Equal(&ConflictError{Method: "GET", Existing: "/users/:id", Pattern: "/users/:name"}))
+			Expect(err.Error()).To(Equal("conflict adding '/users/:name' to GET: it conflicts with '/users/:id'"))
+
+			Expect(router.TryHandle("GET", "/users/*path", emptyHandler)).To(Equal(&ConflictError{Method: "GET", Existing: "/users/:id", Pattern: "/users/*path"}))
+			Expect(router.TryHandle("GET", "/files/:file", emptyHandler)).To(Equal(&ConflictError{Method: "GET", Existing: "/files/*filepath", Pattern: "/files/:file"}))
+			Expect(router.TryHandle("GET", "files/*path", emptyHandler)).To(Equal(&ConflictError{Method: "GET", Existing: "/files/*filepath", Pattern: "/files/*path"}))
+			Expect(router.TryHandle("POST", "/users/:name", emptyHandler)).To(Succeed())
+		})
+
+		It("should return the errors of invalid routes", func() {
+			router := New()
+			Expect(router.TryHandle("", "/users", emptyHandler)).To(MatchError("empty method"))
+			Expect(router.TryHandle("GET", "/users//:id", emptyHandler)).To(MatchError("empty token in '/users//:id'"))
+			Expect(router.TryHandle("GET", "/files/*path/raw", emptyHandler)).To(MatchError("catch-all must be the last segment of '/files/*path/raw'"))
+			Expect(router.TryHandle("GET", "/z/*", emptyHandler)).To(MatchError("invalid parameter '' in '/z/*'"))
+			Expect(router.TryHandle("GET", "/z/:", emptyHandler)).To(MatchError("invalid parameter '' in '/z/:'"))
+			Expect(router.TryHandle("GET", "/users/:id/posts/:id", emptyHandler)).To(MatchError("duplicate parameter 'id' in '/users/:id/posts/:id'"))
+			Expect(router.TryHandle("GET", "/files/:name:ext", emptyHandler)).To(MatchError("ambiguous parameters in '/files/:name:ext'"))
+			Expect(router.TryHandle("GET", "/files/:id<hex>", emptyHandler)).To(MatchError("unknown parameter type 'hex' in '/files/:id<hex>'"))
+			Expect(router.TryHandle("GET", "/files/:id{[0-9}", emptyHandler)).To(HaveOccurred())
+		})
+
+		It("should not register any variant of a failing optional route", func() {
+			router := New()
+			router.GET("/posts", emptyHandler)
+
+			Expect(router.TryHandle("GET", "/posts/:page?", emptyHandler)).To(Equal(&ConflictError{Method: "GET", Existing: "/posts", Pattern: "/posts/:page?"}))
+			Expect(router.TryHandle("GET", "/posts/:id", emptyHandler)).To(Succeed())
+			Expect(router.TryHandle("GET", "/posts/:slug/*rest", emptyHandler)).To(Succeed())
+		})
+
+		It("should not leave the nodes of a failing route in the way", func() {
+			router := New()
+			router.GET("/files/:name/raw", emptyHandler)
+			Expect(router.TryHandle("GET", "/files/:name/raw", emptyHandler)).To(HaveOccurred())
+			Expect(router.TryHandle("GET", "/static/:name/:id<hex>", emptyHandler)).To(HaveOccurred())
+			Expect(router.TryHandle("GET", "/static/*filepath", emptyHandler)).To(Succeed())
+		})
+
+		It("should collect the registration errors", func() {
+			router := New()
+			router.CollectErrors = true
+			Expect(router.Err()).To(BeNil())
+
+			Expect(func() {
+				router.GET("/users/:id", emptyHandler)
+				router.GET("/users/:name", emptyHandler)
+				router.Group("/admin").GET("/a//b", emptyHandler)
+				router.Host("api.example.com").GET("/users/:id", emptyHandler)
+				router.Host("api.example.com").GET("/users/:name", emptyHandler)
+			}).NotTo(Panic())
+
+			err := router.Err()
+			Expect(err).To(HaveOccurred())
+			errs, ok := err.(Errors)
+			Expect(ok).To(BeTrue())
+			Expect(errs).To(HaveLen(3))
+			Expect(errs[0]).To(Equal(&ConflictError{Method: "GET", Existing: "/users/:id", Pattern: "/users/:name"}))
+			Expect(errs[1]).To(MatchError("empty token in '/admin/a//b'"))
+			Expect(errs[2]).To(BeAssignableToTypeOf(&ConflictError{}))
+			Expect(err.Error()).To(Equal(errs[0].Error() + "; " + errs[1].Error() + "; " + errs[2].Error()))
+		})
+
+		It("should panic with the registration error", func() {
+			router := New()
+			router.GET("/users/:id", emptyHandler)
+			Expect(func() {
+				router.GET("/users/:name", emptyHandler)
+			}).To(PanicWith(&ConflictError{Method: "GET", Existing: "/users/:id", Pattern: "/users/:name"}))
+		})
+
+		It("should return the errors of group routes", func() {
+			router := New()
+			group := router.Group("/api")
+			Expect(group.TryHandle("GET", "/users/:id", emptyHandler)).To(Succeed())
+			Expect(group.TryHandle("GET", "/users/:name", emptyHandler)).To(Equal(&ConflictError{Method: "GET", Existing: "/api/users/:id", Pattern: "/api/users/:name"}))
+		})
+
+		It("should parse a custom method", func() {
+			router := New()
+			router.Handle("PROPFIND", "/route", emptyHandler)
+
+			Expect(router.tree("PROPFIND")).NotTo(BeNil())
+			Expect(staticChild(router.tree("PROPFIND"), "route")).NotTo(BeNil())
+			Expect(router.tree("PROPFIND").wildcard).To(BeNil())
+		})
+
+		It("should parse any method", func() {
+			router := New()
+			router.Any("/route", emptyHandler)
+
+			for _, method := range []string{"CONNECT", "DELETE", "GET", "HEAD", "OPTIONS", "PATCH", "POST", "PUT", "TRACE"} {
+				Expect(router.tree(method)).NotTo(BeNil())
+				Expect(staticChild(router.tree(method), "route")).NotTo(BeNil())
+				Expect(staticChild(router.tree(method), "route").handler).NotTo(BeNil())
+			}
+		})
+
+		It("should panic due to an empty method", func() {
+			router := New()
+			Expect(func() {
+				router.Handle("", "/route", emptyHandler)
+			}).To(Panic())
+		})
+
+		It("should parse a route with a trailing slash", func() {
+			router := New()
+			router.GET("/account", emptyHandler)
+			router.GET("/account/", emptyHandler)
+
+			Expect(router.tree("GET").handler).To(BeNil())
+			Expect(staticChild(router.tree("GET"), "account")).NotTo(BeNil())
+			Expect(staticChild(router.tree("GET"), "account").handler).NotTo(BeNil())
+			Expect(staticChild(staticChild(router.tree("GET"), "account"), "/")).NotTo(BeNil())
+			Expect(staticChild(staticChild(router.tree("GET"), "account"), "/").handler).NotTo(BeNil())
 		})
 
 		It("should panic due to conflicting empty tokens", func() {
@@ -406,17 +854,13 @@ var _ = Describe("Router", func() {
 			group := router.Group("/group")
 			group.GET("/route", emptyHandler)
 
-			Expect(router.children).To(HaveKey("GET"))
-			Expect(router.children["GET"].wildcard).To(BeNil())
-			Expect(router.children["GET"].children).To(HaveLen(1))
-			Expect(router.children["GET"].children).To(HaveKey("group"))
-			Expect(router.children["GET"].children["group"].wildcard).To(BeNil())
-			Expect(router.children["GET"].children["group"].handler).To(BeNil())
-			Expect(router.children["GET"].children["group"].children).To(HaveLen(1))
-			Expect(router.children["GET"].children["group"].children).To(HaveKey("route"))
-			Expect(router.children["GET"].children["group"].children["route"].wildcard).To(BeNil())
-			Expect(router.children["GET"].children["group"].children["route"].handler).NotTo(BeNil())
-			Expect(router.children["GET"].children["group"].children["route"].children).To(BeEmpty())
+			Expect(router.tree("GET")).NotTo(BeNil())
+			Expect(router.tree("GET").wildcard).To(BeNil())
+			Expect(router.tree("GET").children).To(HaveLen(1))
+			Expect(staticChild(router.tree("GET"), "group/route")).NotTo(BeNil())
+			Expect(staticChild(router.tree("GET"), "group/route").wildcard).To(BeNil())
+			Expect(staticChild(router.tree("GET"), "group/route").handler).NotTo(BeNil())
+			Expect(staticChild(router.tree("GET"), "group/route").children).To(BeEmpty())
 		})
 
 		It("should parse a POST", func() {
@@ -424,17 +868,13 @@ var _ = Describe("Router", func() {
 			group := router.Group("/group")
 			group.POST("/route", emptyHandler)
 
-			Expect(router.children).To(HaveKey("POST"))
-			Expect(router.children["POST"].wildcard).To(BeNil())
-			Expect(router.children["POST"].children).To(HaveLen(1))
-			Expect(router.children["POST"].children).To(HaveKey("group"))
-			Expect(router.children["POST"].children["group"].wildcard).To(BeNil())
-			Expect(router.children["POST"].children["group"].handler).To(BeNil())
-			Expect(router.children["POST"].children["group"].children).To(HaveLen(1))
-			Expect(router.children["POST"].children["group"].children).To(HaveKey("route"))
-			Expect(router.children["POST"].children["group"].children["route"].wildcard).To(BeNil())
-			Expect(router.children["POST"].children["group"].children["route"].handler).NotTo(BeNil())
-			Expect(router.children["POST"].children["group"].children["route"].children).To(BeEmpty())
+			Expect(router.tree("POST")).NotTo(BeNil())
+			Expect(router.tree("POST").wildcard).To(BeNil())
+			Expect(router.tree("POST").children).To(HaveLen(1))
+			Expect(staticChild(router.tree("POST"), "group/route")).NotTo(BeNil())
+			Expect(staticChild(router.tree("POST"), "group/route").wildcard).To(BeNil())
+			Expect(staticChild(router.tree("POST"), "group/route").handler).NotTo(BeNil())
+			Expect(staticChild(router.tree("POST"), "group/route").children).To(BeEmpty())
 		})
 
 		It("should parse a PUT", func() {
@@ -442,17 +882,13 @@ var _ = Describe("Router", func() {
 			group := router.Group("/group")
 			group.PUT("/route", emptyHandler)
 
-			Expect(router.children).To(HaveKey("PUT"))
-			Expect(router.children["PUT"].wildcard).To(BeNil())
-			Expect(router.children["PUT"].children).To(HaveLen(1))
-			Expect(router.children["PUT"].children).To(HaveKey("group"))
-			Expect(router.children["PUT"].children["group"].wildcard).To(BeNil())
-			Expect(router.children["PUT"].children["group"].handler).To(BeNil())
-			Expect(router.children["PUT"].children["group"].children).To(HaveLen(1))
-			Expect(router.children["PUT"].children["group"].children).To(HaveKey("route"))
-			Expect(router.children["PUT"].children["group"].children["route"].wildcard).To(BeNil())
-			Expect(router.children["PUT"].children["group"].children["route"].handler).NotTo(BeNil())
-			Expect(router.children["PUT"].children["group"].children["route"].children).To(BeEmpty())
+			Expect(router.tree("PUT")).NotTo(BeNil())
+			Expect(router.tree("PUT").wildcard).To(BeNil())
+			Expect(router.tree("PUT").children).To(HaveLen(1))
+			Expect(staticChild(router.tree("PUT"), "group/route")).NotTo(BeNil())
+			Expect(staticChild(router.tree("PUT"), "group/route").wildcard).To(BeNil())
+			Expect(staticChild(router.tree("PUT"), "group/route").handler).NotTo(BeNil())
+			Expect(staticChild(router.tree("PUT"), "group/route").children).To(BeEmpty())
 		})
 
 		It("should parse a DELETE", func() {
@@ -460,17 +896,13 @@ var _ = Describe("Router", func() {
 			group := router.Group("/group")
 			group.DELETE("/route", emptyHandler)
 
-			Expect(router.children).To(HaveKey("DELETE"))
-			Expect(router.children["DELETE"].wildcard).To(BeNil())
-			Expect(router.children["DELETE"].children).To(HaveLen(1))
-			Expect(router.children["DELETE"].children).To(HaveKey("group"))
-			Expect(router.children["DELETE"].children["group"].wildcard).To(BeNil())
-			Expect(router.children["DELETE"].children["group"].handler).To(BeNil())
-			Expect(router.children["DELETE"].children["group"].children).To(HaveLen(1))
-			Expect(router.children["DELETE"].children["group"].children).To(HaveKey("route"))
-			Expect(router.children["DELETE"].children["group"].children["route"].wildcard).To(BeNil())
-			Expect(router.children["DELETE"].children["group"].children["route"].handler).NotTo(BeNil())
-			Expect(router.children["DELETE"].children["group"].children["route"].children).To(BeEmpty())
+			Expect(router.tree("DELETE")).NotTo(BeNil())
+			Expect(router.tree("DELETE").wildcard).To(BeNil())
+			Expect(router.tree("DELETE").children).To(HaveLen(1))
+			Expect(staticChild(router.tree("DELETE"), "group/route")).NotTo(BeNil())
+			Expect(staticChild(router.tree("DELETE"), "group/route").wildcard).To(BeNil())
+			Expect(staticChild(router.tree("DELETE"), "group/route").handler).NotTo(BeNil())
+			Expect(staticChild(router.tree("DELETE"), "group/route").children).To(BeEmpty())
 		})
 
 		It("should parse a HEAD", func() {
@@ -478,17 +910,13 @@ var _ = Describe("Router", func() {
 			group := router.Group("/group")
 			group.HEAD("/route", emptyHandler)
 
-			Expect(router.children).To(HaveKey("HEAD"))
-			Expect(router.children["HEAD"].wildcard).To(BeNil())
-			Expect(router.children["HEAD"].children).To(HaveLen(1))
-			Expect(router.children["HEAD"].children).To(HaveKey("group"))
-			Expect(router.children["HEAD"].children["group"].wildcard).To(BeNil())
-			Expect(router.children["HEAD"].children["group"].handler).To(BeNil())
-			Expect(router.children["HEAD"].children["group"].children).To(HaveLen(1))
-			Expect(router.children["HEAD"].children["group"].children).To(HaveKey("route"))
-			Expect(router.children["HEAD"].children["group"].children["route"].wildcard).To(BeNil())
-			Expect(router.children["HEAD"].children["group"].children["route"].handler).NotTo(BeNil())
-			Expect(router.children["HEAD"].children["group"].children["route"].children).To(BeEmpty())
+			Expect(router.tree("HEAD")).NotTo(BeNil())
+			Expect(router.tree("HEAD").wildcard).To(BeNil())
+			Expect(router.tree("HEAD").children).To(HaveLen(1))
+			Expect(staticChild(router.tree("HEAD"), "group/route")).NotTo(BeNil())
+			Expect(staticChild(router.tree("HEAD"), "group/route").wildcard).To(BeNil())
+			Expect(staticChild(router.tree("HEAD"), "group/route").handler).NotTo(BeNil())
+			Expect(staticChild(router.tree("HEAD"), "group/route").children).To(BeEmpty())
 		})
 
 		It("should parse a OPTIONS", func() {
@@ -496,17 +924,13 @@ var _ = Describe("Router", func() {
 			group := router.Group("/group")
 			group.OPTIONS("/route", emptyHandler)
 
-			Expect(router.children).To(HaveKey("OPTIONS"))
-			Expect(router.children["OPTIONS"].wildcard).To(BeNil())
-			Expect(router.children["OPTIONS"].children).To(HaveLen(1))
-			Expect(router.children["OPTIONS"].children).To(HaveKey("group"))
-			Expect(router.children["OPTIONS"].children["group"].wildcard).To(BeNil())
-			Expect(router.children["OPTIONS"].children["group"].handler).To(BeNil())
-			Expect(router.children["OPTIONS"].children["group"].children).To(HaveLen(1))
-			Expect(router.children["OPTIONS"].children["group"].children).To(HaveKey("route"))
-			Expect(router.children["OPTIONS"].children["group"].children["route"].wildcard).To(BeNil())
-			Expect(router.children["OPTIONS"].children["group"].children["route"].handler).NotTo(BeNil())
-			Expect(router.children["OPTIONS"].children["group"].children["route"].children).To(BeEmpty())
+			Expect(router.tree("OPTIONS")).NotTo(BeNil())
+			Expect(router.tree("OPTIONS").wildcard).To(BeNil())
+			Expect(router.tree("OPTIONS").children).To(HaveLen(1))
+			Expect(staticChild(router.tree("OPTIONS"), "group/route")).NotTo(BeNil())
+			Expect(staticChild(router.tree("OPTIONS"), "group/route").wildcard).To(BeNil())
+			Expect(staticChild(router.tree("OPTIONS"), "group/route").handler).NotTo(BeNil())
+			Expect(staticChild(router.tree("OPTIONS"), "group/route").children).To(BeEmpty())
 		})
 
 		It("should parse a PATCH", func() {
@@ -514,17 +938,135 @@ var _ = Describe("Router", func() {
 			group := router.Group("/group")
 			group.PATCH("/route", emptyHandler)
 
-			Expect(router.children).To(HaveKey("PATCH"))
-			Expect(router.children["PATCH"].wildcard).To(BeNil())
-			Expect(router.children["PATCH"].children).To(HaveLen(1))
-			Expect(router.children["PATCH"].children).To(HaveKey("group"))
-			Expect(router.children["PATCH"].children["group"].wildcard).To(BeNil())
-			Expect(router.children["PATCH"].children["group"].handler).To(BeNil())
-			Expect(router.children["PATCH"].children["group"].children).To(HaveLen(1))
-			Expect(router.children["PATCH"].children["group"].children).To(HaveKey("route"))
-			Expect(router.children["PATCH"].children["group"].children["route"].wildcard).To(BeNil())
-			Expect(router.children["PATCH"].children["group"].children["route"].handler).NotTo(BeNil())
-			Expect(router.children["PATCH"].children["group"].children["route"].children).To(BeEmpty())
+			Expect(router.tree("PATCH")).NotTo(BeNil())
+			Expect(router.tree("PATCH").wildcard).To(BeNil())
+			Expect(router.tree("PATCH").children).To(HaveLen(1))
+			Expect(staticChild(router.tree("PATCH"), "group/route")).NotTo(BeNil())
+			Expect(staticChild(router.tree("PATCH"), "group/route").wildcard).To(BeNil())
+			Expect(staticChild(router.tree("PATCH"), "group/route").handler).NotTo(BeNil())
+			Expect(staticChild(router.tree("PATCH"), "group/route").children).To(BeEmpty())
+		})
+
+		It("should parse a custom method", func() {
+			router := New()
+			group := router.Group("/group")
+			group.Handle("MKCOL", "/route", emptyHandler)
+
+			Expect(router.tree("MKCOL")).NotTo(BeNil())
+			Expect(staticChild(router.tree("MKCOL"), "group/route")).NotTo(BeNil())
+			Expect(staticChild(router.tree("MKCOL"), "group/route").handler).NotTo(BeNil())
+		})
+
+		It("should parse any method", func() {
+			router := New()
+			group := router.Group("/group")
+			group.Any("/route", emptyHandler)
+
+			for _, method := range []string{"CONNECT", "DELETE", "GET", "HEAD", "OPTIONS", "PATCH", "POST", "PUT", "TRACE"} {
+				Expect(router.tree(method)).NotTo(BeNil())
+				Expect(staticChild(router.tree(method), "group/route")).NotTo(BeNil())
+			}
+		})
+
+		It("should join the group prefixes segment wise", func() {
+			router := New()
+			for _, prefix := range []string{"/api/", "/api", "api", "api/"} {
+				group := router.Group(prefix)
+				Expect(group.(*routerGroup).prefix).To(Equal("/api"))
+				Expect(group.(*routerGroup).path("/users")).To(Equal("/api/users"))
+				Expect(group.(*routerGroup).path("users")).To(Equal("/api/users"))
+				Expect(group.(*routerGroup).path("users/")).To(Equal("/api/users/"))
+				Expect(group.(*routerGroup).path("/")).To(Equal("/api/"))
+				Expect(group.(*routerGroup).path("")).To(Equal("/api"))
+			}
+			for _, prefix := range []string{"", "/", "//"} {
+				group := router.Group(prefix)
+				Expect(group.(*routerGroup).prefix).To(BeEmpty())
+				Expect(group.(*routerGroup).path("users")).To(Equal("/users"))
+				Expect(group.(*routerGroup).path("")).To(BeEmpty())
+			}
+		})
+
+		It("should join the prefixes of nested groups", func() {
+			router := New()
+			group := router.Group("/api/").Group("v1/").Group("").Group("/users/")
+			Expect(group.(*routerGroup).path(":id")).To(Equal("/api/v1/users/:id"))
+			Expect(group.(*routerGroup).path("")).To(Equal("/api/v1/users"))
+
+			group.GET("", emptyHandler)
+			group.GET(":id", emptyHandler)
+			Expect(staticChild(router.tree("GET"), "api/v1/users")).NotTo(BeNil())
+			Expect(staticChild(router.tree("GET"), "api/v1/users").handler).NotTo(BeNil())
+		})
+
+		It("should resolve groups with parameter prefixes", func() {
+			var called string
+			router := New()
+			tenant := router.Group("/tenants/:tenant<int>/")
+			tenant.Group("projects").GET(":id", func(ctx *fasthttp.RequestCtx) {
+				called = string(GetParams(ctx).ByName("tenant")) + "/" + string(GetParams(ctx).ByName("id"))
+			})
+			router.Group("/(:lang)").GET("/docs", func(ctx *fasthttp.RequestCtx) {
+				called = "docs:" + string(GetParams(ctx).ByName("lang"))
+			})
+
+			router.Handler(createRequestCtxFromPath("GET", "/tenants/1/projects/2"))
+			Expect(called).To(Equal("1/2"))
+			router.Handler(createRequestCtxFromPath("GET", "/docs"))
+			Expect(called).To(Equal("docs:"))
+			router.Handler(createRequestCtxFromPath("GET", "/pt/docs"))
+			Expect(called).To(Equal("docs:pt"))
+		})
+
+		It("should panic due to invalid group prefixes", func() {
+			router := New()
+			Expect(func() {
+				router.Group("/api//v1")
+			}).To(Panic())
+			Expect(func() {
+				router.Group("/static/*filepath")
+			}).To(Panic())
+			Expect(func() {
+				router.Group("/api").Group("/:id{[0-9}")
+			}).To(Panic())
+			Expect(func() {
+				router.Group("/files/:name:ext")
+			}).To(Panic())
+			Expect(func() {
+				router.Group("/files/:name.:ext/:id<int>")
+			}).NotTo(Panic())
+		})
+
+		It("should collect the errors of invalid group prefixes", func() {
+			router := New()
+			router.CollectErrors = true
+			router.Group("/api//v1")
+			router.Host("api.example.com").Group("/static/*filepath")
+
+			Expect(router.Err()).To(MatchError("empty token in group prefix '/api//v1'; catch-all in group prefix '/static/*filepath'"))
+		})
+
+		It("should collect the errors of invalid host patterns", func() {
+			router := New()
+			router.CollectErrors = true
+			Expect(func() {
+				router.Host("api..example.com").GET("/users", emptyHandler)
+			}).NotTo(Panic())
+
+			Expect(router.Err()).To(MatchError("invalid host pattern 'api..example.com'"))
+			Expect(router.Routes()).To(BeEmpty())
+		})
+
+		It("should reject the parameters repeated across nested groups", func() {
+			router := New()
+			group := router.Group("/x").Group("/:ver")
+			Expect(group.TryHandle("GET", "/:ver", emptyHandler)).To(MatchError("duplicate parameter 'ver' in '/x/:ver/:ver'"))
+			Expect(group.TryHandle("GET", "/files/*ver", emptyHandler)).To(MatchError("duplicate parameter 'ver' in '/x/:ver/files/*ver'"))
+			Expect(group.TryHandle("GET", "/:id", emptyHandler)).To(Succeed())
+
+			router.CollectErrors = true
+			router.Group("/x").Group("/:ver").GET("/users/:ver<int>", emptyHandler)
+			Expect(router.Err()).To(MatchError("duplicate parameter 'ver' in '/x/:ver/users/:ver<int>'"))
 		})
 
 		It("should check the subgroup", func() {
@@ -533,11 +1075,128 @@ var _ = Describe("Router", func() {
 			group2 := group.Group("/subgroup").(*routerGroup)
 
 			Expect(group2).NotTo(BeNil())
-			Expect(group2.router).To(Equal(group))
+			Expect(group2.parent).To(Equal(group))
+			Expect(group2.root).To(Equal(router))
 			Expect(group2.prefix).To(Equal("/subgroup"))
 		})
 	})
 
+	Describe("Routes", func() {
+		It("should list the routes", func() {
+			router := New()
+			router.POST("/users", emptyHandler)
+			router.GET("/users/:id", routeHandler)
+			router.GET("/users", emptyHandler)
+			router.GET("/files/*filepath", emptyHandler)
+			router.Handle("PROPFIND", "/users/:id", emptyHandler)
+
+			routes := router.Routes()
+			Expect(routes).To(HaveLen(5))
+			Expect(routes[0].Method).To(Equal("GET"))
+			Expect(routes[0].Pattern).To(Equal("/users"))
+			Expect(routes[1].Pattern).To(Equal("/users/:id"))
+			Expect(routes[1].Path).To(Equal("/users/:id"))
+			Expect(routes[1].Params).To(Equal([]string{"id"}))
+			Expect(routes[1].Handler).To(HaveSuffix(".routeHandler"))
+			Expect(routes[2].Pattern).To(Equal("/files/*filepath"))
+			Expect(routes[2].Params).To(Equal([]string{"filepath"}))
+			Expect(routes[3].Method).To(Equal("POST"))
+			Expect(routes[4].Method).To(Equal("PROPFIND"))
+			Expect(routes[4].Host).To(BeEmpty())
+		})
+
+		It("should report the handlers wrapped by middlewares", func() {
+			calls := make([]string, 0)
+			router := New()
+			router.Host("api.example.com").Use(traceMiddleware(&calls, "host"))
+			group := router.Group("/api", traceMiddleware(&calls, "group"))
+			group.GET("/users/:id", routeHandler, traceMiddleware(&calls, "route"))
+			router.Host("api.example.com").GET("/users", routeHandler)
+
+			routes := router.Routes()
+			Expect(routes).To(HaveLen(2))
+			Expect(routes[0].Handler).To(HaveSuffix(".routeHandler"))
+			Expect(routes[1].Handler).To(HaveSuffix(".routeHandler"))
+		})
+
+		It("should list every variant of optional routes", func() {
+			router := New()
+			router.Group("/api").GET("/posts/:page?", emptyHandler)
+
+			routes := router.Routes()
+			Expect(routes).To(HaveLen(2))
+			Expect(routes[0].Pattern).To(Equal("/api/posts/:page?"))
+			Expect(routes[0].Path).To(Equal("/api/posts"))
+			Expect(routes[0].Params).To(BeEmpty())
+			Expect(routes[1].Pattern).To(Equal("/api/posts/:page?"))
+			Expect(routes[1].Path).To(Equal("/api/posts/:page"))
+			Expect(routes[1].Params).To(Equal([]string{"page"}))
+		})
+
+		It("should list the host routes after the routes of the router", func() {
+			router := New()
+			router.Host(":tenant.example.com").GET("/", emptyHandler)
+			router.GET("/", emptyHandler)
+
+			routes := router.Routes()
+			Expect(routes).To(HaveLen(2))
+			Expect(routes[0].Host).To(BeEmpty())
+			Expect(routes[1].Host).To(Equal(":tenant.example.com"))
+			Expect(routes[1].Pattern).To(Equal("/"))
+		})
+
+		It("should list the mounted routes after the routes of the router", func() {
+			router := New()
+			router.GET("/", emptyHandler)
+			billing := New()
+			billing.GET("/invoices/:id", routeHandler)
+			billing.MountHandler("/legacy", emptyHandler)
+			router.Mount("/billing", billing)
+			router.MountHandler("/static/", routeHandler)
+
+			routes := router.Routes()
+			Expect(routes).To(HaveLen(4))
+			Expect(routes[0].Pattern).To(Equal("/"))
+			Expect(routes[1].Method).To(Equal("GET"))
+			Expect(routes[1].Pattern).To(Equal("/billing/invoices/:id"))
+			Expect(routes[1].Path).To(Equal("/billing/invoices/:id"))
+			Expect(routes[1].Params).To(Equal([]string{"id"}))
+			Expect(routes[1].Handler).To(HaveSuffix(".routeHandler"))
+			Expect(routes[2].Method).To(BeEmpty())
+			Expect(routes[2].Pattern).To(Equal("/billing/legacy"))
+			Expect(routes[3].Method).To(BeEmpty())
+			Expect(routes[3].Pattern).To(Equal("/static"))
+			Expect(routes[3].Path).To(Equal("/static"))
+			Expect(routes[3].Handler).To(HaveSuffix(".routeHandler"))
+		})
+
+		It("should not list failed registrations", func() {
+			router := New()
+			router.GET("/posts", emptyHandler)
+			Expect(router.TryHandle("GET", "/posts/:page?", emptyHandler)).To(HaveOccurred())
+
+			Expect(router.Routes()).To(HaveLen(1))
+		})
+
+		It("should stop walking at the first error", func() {
+			router := New()
+			router.GET("/a", emptyHandler)
+			router.GET("/b", emptyHandler)
+			router.GET("/c", emptyHandler)
+
+			visited := 0
+			stop := fmt.Errorf("stop")
+			Expect(router.Walk(func(route RouteInfo) error {
+				visited++
+				if route.Pattern == "/b" {
+					return stop
+				}
+				return nil
+			})).To(Equal(stop))
+			Expect(visited).To(Equal(2))
+		})
+	})
+
 	Describe("Handle", func() {
 		var router *Router
 
@@ -682,11 +1341,198 @@ var _ = Describe("Router", func() {
 			Expect(value3).To(Equal(2))
 		})
 
-		It("should call the not found callback for the index route", func() {
+		It("should fallback to the wildcard when the static route does not match", func() {
 			value1 := 1
-
-			router.GET("/account/transactions", func(ctx *fasthttp.RequestCtx) {
-				Fail("should not be called")
+			value2 := 1
+			router.GET("/users/new/edit", func(ctx *fasthttp.RequestCtx) {
+				value1 = 2
+			})
+			router.GET("/users/:id/profile", func(ctx *fasthttp.RequestCtx) {
+				Expect(ctx.UserValue("id")).To(Equal("new"))
+				value2 = 2
+			})
+
+			router.Handler(createRequestCtxFromPath("GET", "/users/new/profile"))
+			Expect(value1).To(Equal(1))
+			Expect(value2).To(Equal(2))
+
+			router.Handler(createRequestCtxFromPath("GET", "/users/new/edit"))
+			Expect(value1).To(Equal(2))
+		})
+
+		It("should fallback to the wildcard when the static route has no handler", func() {
+			value1 := 1
+			router.GET("/users/new/edit", func(ctx *fasthttp.RequestCtx) {
+				Fail("should not be called")
+			})
+			router.GET("/users/:id", func(ctx *fasthttp.RequestCtx) {
+				Expect(ctx.UserValue("id")).To(Equal("new"))
+				value1 = 2
+			})
+
+			router.Handler(createRequestCtxFromPath("GET", "/users/new"))
+
+			Expect(value1).To(Equal(2))
+		})
+
+		It("should backtrack through deep mixed routes", func() {
+			value1 := 1
+			value2 := 1
+			value3 := 1
+			router.GET("/a/b/c/d", func(ctx *fasthttp.RequestCtx) {
+				Fail("should not be called")
+			})
+			router.GET("/a/:p1/c/e", func(ctx *fasthttp.RequestCtx) {
+				Expect(ctx.UserValue("p1")).To(Equal("b"))
+				value1 = 2
+			})
+			router.GET("/a/b/:p2/f", func(ctx *fasthttp.RequestCtx) {
+				Expect(ctx.UserValue("p2")).To(Equal("c"))
+				value2 = 2
+			})
+			router.GET("/a/:p1/:p3/g", func(ctx *fasthttp.RequestCtx) {
+				Expect(ctx.UserValue("p1")).To(Equal("b"))
+				Expect(ctx.UserValue("p3")).To(Equal("c"))
+				value3 = 2
+			})
+			router.NotFound = func(ctx *fasthttp.RequestCtx) {
+				Fail("should not be called")
+			}
+
+			router.Handler(createRequestCtxFromPath("GET", "/a/b/c/e"))
+			router.Handler(createRequestCtxFromPath("GET", "/a/b/c/f"))
+			router.Handler(createRequestCtxFromPath("GET", "/a/b/c/g"))
+
+			Expect(value1).To(Equal(2))
+			Expect(value2).To(Equal(2))
+			Expect(value3).To(Equal(2))
+		})
+
+		It("should not leak values from a failed static branch", func() {
+			value1 := 1
+			router.GET("/:a/x/:b/y", func(ctx *fasthttp.RequestCtx) {
+				Fail("should not be called")
+			})
+			router.GET("/:a/:c/z", func(ctx *fasthttp.RequestCtx) {
+				Expect(ctx.UserValue("a")).To(Equal("1"))
+				Expect(ctx.UserValue("c")).To(Equal("x"))
+				Expect(ctx.UserValue("b")).To(BeNil())
+				value1 = 2
+			})
+
+			router.Handler(createRequestCtxFromPath("GET", "/1/x/z"))
+
+			Expect(value1).To(Equal(2))
+		})
+
+		It("should call the not found callback when no branch matches", func() {
+			value1 := 1
+			router.GET("/users/new/edit", func(ctx *fasthttp.RequestCtx) {
+				Fail("should not be called")
+			})
+			router.GET("/users/:id/profile", func(ctx *fasthttp.RequestCtx) {
+				Fail("should not be called")
+			})
+
+			router.NotFound = func(ctx *fasthttp.RequestCtx) {
+				value1 = 2
+			}
+			router.Handler(createRequestCtxFromPath("GET", "/users/new/settings"))
+
+			Expect(value1).To(Equal(2))
+		})
+
+		It("should resolve a catch-all route", func() {
+			value1 := 1
+			router.GET("/static/*filepath", func(ctx *fasthttp.RequestCtx) {
+				Expect(ctx.UserValue("filepath")).To(Equal("css/site/main.css"))
+				value1 = 2
+			})
+
+			router.Handler(createRequestCtxFromPath("GET", "/static/css/site/main.css"))
+
+			Expect(value1).To(Equal(2))
+		})
+
+		It("should resolve a catch-all route keeping the trailing slash", func() {
+			value1 := 1
+			router.GET("/static/*filepath", func(ctx *fasthttp.RequestCtx) {
+				Expect(ctx.UserValue("filepath")).To(Equal("css/"))
+				value1 = 2
+			})
+
+			router.Handler(createRequestCtxFromPath("GET", "/static/css/"))
+
+			Expect(value1).To(Equal(2))
+		})
+
+		It("should resolve a catch-all route with an empty remainder", func() {
+			value1 := 0
+			router.GET("/static/*filepath", func(ctx *fasthttp.RequestCtx) {
+				Expect(ctx.UserValue("filepath")).To(Equal(""))
+				value1++
+			})
+
+			router.Handler(createRequestCtxFromPath("GET", "/static/"))
+			router.Handler(createRequestCtxFromPath("GET", "/static"))
+
+			Expect(value1).To(Equal(2))
+		})
+
+		It("should resolve a catch-all route on the root", func() {
+			value1 := 0
+			router.GET("/*filepath", func(ctx *fasthttp.RequestCtx) {
+				value1++
+			})
+
+			router.Handler(createRequestCtxFromPath("GET", "/"))
+			router.Handler(createRequestCtxFromPath("GET", "/index.html"))
+
+			Expect(value1).To(Equal(2))
+		})
+
+		It("should resolve a catch-all route with wildcards", func() {
+			value1 := 1
+			router.GET("/:account/files/*filepath", func(ctx *fasthttp.RequestCtx) {
+				Expect(ctx.UserValue("account")).To(Equal("account1"))
+				Expect(ctx.UserValue("filepath")).To(Equal("a/b"))
+				value1 = 2
+			})
+
+			router.Handler(createRequestCtxFromPath("GET", "/account1/files/a/b"))
+
+			Expect(value1).To(Equal(2))
+		})
+
+		It("should prefer static routes to the catch-all", func() {
+			value1 := 1
+			value2 := 1
+			value3 := 1
+			router.GET("/static", func(ctx *fasthttp.RequestCtx) {
+				value1 = 2
+			})
+			router.GET("/static/index.html", func(ctx *fasthttp.RequestCtx) {
+				value2 = 2
+			})
+			router.GET("/static/*filepath", func(ctx *fasthttp.RequestCtx) {
+				Expect(ctx.UserValue("filepath")).To(Equal("index.html/other"))
+				value3 = 2
+			})
+
+			router.Handler(createRequestCtxFromPath("GET", "/static"))
+			router.Handler(createRequestCtxFromPath("GET", "/static/index.html"))
+			router.Handler(createRequestCtxFromPath("GET", "/static/index.html/other"))
+
+			Expect(value1).To(Equal(2))
+			Expect(value2).To(Equal(2))
+			Expect(value3).To(Equal(2))
+		})
+
+		It("should call the not found callback for the index route", func() {
+			value1 := 1
+
+			router.GET("/account/transactions", func(ctx *fasthttp.RequestCtx) {
+				Fail("should not be called")
 			})
 
 			router.NotFound = func(ctx *fasthttp.RequestCtx) {
@@ -791,26 +1637,1653 @@ var _ = Describe("Router", func() {
 
 			Expect(value1).To(Equal(2))
 		})
-	})
-})
 
-func BenchmarkSplit(b *testing.B) {
-	path := []byte("/path/with/four/parts")
-	tokens := make([][]byte, 0)
+		It("should call the method not allowed callback for a route of another method", func() {
+			value1 := 1
 
-	for i := 0; i < b.N; i++ {
-		tokens = Split(path, tokens)
-		tokens = tokens[0:0]
-	}
-}
+			router.GET("/:account/transactions", func(ctx *fasthttp.RequestCtx) {
+				Fail("should not be called")
+			})
+			router.PUT("/:account/transactions", func(ctx *fasthttp.RequestCtx) {
+				Fail("should not be called")
+			})
+			router.DELETE("/:account/profile", func(ctx *fasthttp.RequestCtx) {
+				Fail("should not be called")
+			})
 
-func BenchmarkRouter_Handler(b *testing.B) {
-	router := New()
-	router.GET("/", emptyHandler)
-	ctx := fasthttp.RequestCtx{}
-	ctx.Request.SetRequestURI("/")
+			router.NotFound = func(ctx *fasthttp.RequestCtx) {
+				Fail("should not be called")
+			}
+			router.MethodNotAllowed = func(ctx *fasthttp.RequestCtx) {
+				Expect(ctx.Response.StatusCode()).To(Equal(fasthttp.StatusMethodNotAllowed))
+				Expect(string(ctx.Response.Header.Peek("Allow"))).To(Equal("GET, HEAD, PUT"))
+				value1 = 2
+			}
+			router.Handler(createRequestCtxFromPath("POST", "/value1/transactions"))
 
-	for i := 0; i < b.N; i++ {
-		router.Handler(&ctx)
+			Expect(value1).To(Equal(2))
+		})
+
+		It("should answer 405 with the allow header when no callback is set", func() {
+			router.GET("/account", emptyHandler)
+			router.PATCH("/account", emptyHandler)
+
+			ctx := createRequestCtxFromPath("POST", "/account")
+			router.Handler(ctx)
+
+			Expect(ctx.Response.StatusCode()).To(Equal(fasthttp.StatusMethodNotAllowed))
+			Expect(string(ctx.Response.Header.Peek("Allow"))).To(Equal("GET, HEAD, PATCH"))
+		})
+
+		It("should call the not found callback when method not allowed is disabled", func() {
+			value1 := 1
+
+			router.GET("/account", func(ctx *fasthttp.RequestCtx) {
+				Fail("should not be called")
+			})
+
+			router.HandleMethodNotAllowed = false
+			router.MethodNotAllowed = func(ctx *fasthttp.RequestCtx) {
+				Fail("should not be called")
+			}
+			router.NotFound = func(ctx *fasthttp.RequestCtx) {
+				value1 = 2
+			}
+			ctx := createRequestCtxFromPath("POST", "/account")
+			router.Handler(ctx)
+
+			Expect(value1).To(Equal(2))
+			Expect(ctx.Response.Header.Peek("Allow")).To(BeEmpty())
+		})
+
+		It("should answer OPTIONS automatically", func() {
+			router.GET("/account/:id", emptyHandler)
+			router.PUT("/account/:id", emptyHandler)
+			router.DELETE("/account", emptyHandler)
+			router.HandleOPTIONS = true
+
+			ctx := createRequestCtxFromPath("OPTIONS", "/account/1")
+			router.Handler(ctx)
+
+			Expect(ctx.Response.StatusCode()).To(Equal(fasthttp.StatusOK))
+			Expect(string(ctx.Response.Header.Peek("Allow"))).To(Equal("GET, HEAD, OPTIONS, PUT"))
+		})
+
+		It("should call the global OPTIONS callback", func() {
+			value1 := 1
+			router.GET("/account/:id", emptyHandler)
+			router.HandleOPTIONS = true
+			router.GlobalOPTIONS = func(ctx *fasthttp.RequestCtx) {
+				Expect(string(ctx.Response.Header.Peek("Allow"))).To(Equal("GET, HEAD, OPTIONS"))
+				ctx.Response.Header.Set("Access-Control-Allow-Methods", string(ctx.Response.Header.Peek("Allow")))
+				ctx.SetStatusCode(fasthttp.StatusNoContent)
+				value1 = 2
+			}
+
+			ctx := createRequestCtxFromPath("OPTIONS", "/account/1")
+			router.Handler(ctx)
+
+			Expect(value1).To(Equal(2))
+			Expect(ctx.Response.StatusCode()).To(Equal(fasthttp.StatusNoContent))
+			Expect(string(ctx.Response.Header.Peek("Access-Control-Allow-Methods"))).To(Equal("GET, HEAD, OPTIONS"))
+		})
+
+		It("should prefer an explicit OPTIONS route", func() {
+			value1 := 1
+			router.GET("/account/:id", emptyHandler)
+			router.OPTIONS("/account/:id", func(ctx *fasthttp.RequestCtx) {
+				value1 = 2
+			})
+			router.HandleOPTIONS = true
+			router.GlobalOPTIONS = func(ctx *fasthttp.RequestCtx) {
+				Fail("should not be called")
+			}
+
+			router.Handler(createRequestCtxFromPath("OPTIONS", "/account/1"))
+
+			Expect(value1).To(Equal(2))
+		})
+
+		It("should list OPTIONS in the allow header of a 405", func() {
+			router.GET("/account", emptyHandler)
+			router.HandleOPTIONS = true
+
+			ctx := createRequestCtxFromPath("POST", "/account")
+			router.Handler(ctx)
+
+			Expect(ctx.Response.StatusCode()).To(Equal(fasthttp.StatusMethodNotAllowed))
+			Expect(string(ctx.Response.Header.Peek("Allow"))).To(Equal("GET, HEAD, OPTIONS"))
+		})
+
+		It("should not answer OPTIONS automatically by default", func() {
+			value1 := 1
+			router.GET("/account", emptyHandler)
+			router.GlobalOPTIONS = func(ctx *fasthttp.RequestCtx) {
+				Fail("should not be called")
+			}
+
+			ctx := createRequestCtxFromPath("OPTIONS", "/account")
+			router.MethodNotAllowed = func(ctx *fasthttp.RequestCtx) {
+				value1 = 2
+			}
+			router.Handler(ctx)
+
+			Expect(value1).To(Equal(2))
+		})
+
+		It("should call the not found callback for OPTIONS of an unknown path", func() {
+			value1 := 1
+			router.GET("/account", emptyHandler)
+			router.HandleOPTIONS = true
+			router.GlobalOPTIONS = func(ctx *fasthttp.RequestCtx) {
+				Fail("should not be called")
+			}
+			router.NotFound = func(ctx *fasthttp.RequestCtx) {
+				value1 = 2
+			}
+
+			router.Handler(createRequestCtxFromPath("OPTIONS", "/profile"))
+
+			Expect(value1).To(Equal(2))
+		})
+
+		It("should serve HEAD with the GET handler", func() {
+			value1 := 1
+			router.GET("/account/:id", func(ctx *fasthttp.RequestCtx) {
+				Expect(ctx.UserValue("id")).To(Equal("1"))
+				ctx.SetBodyString("body")
+				value1 = 2
+			})
+
+			ctx := createRequestCtxFromPath("HEAD", "/account/1")
+			router.Handler(ctx)
+
+			Expect(value1).To(Equal(2))
+			Expect(ctx.Response.SkipBody).To(BeTrue())
+		})
+
+		It("should prefer an explicit HEAD route", func() {
+			value1 := 1
+			router.GET("/account/:id", func(ctx *fasthttp.RequestCtx) {
+				Fail("should not be called")
+			})
+			router.HEAD("/account/:id", func(ctx *fasthttp.RequestCtx) {
+				value1 = 2
+			})
+
+			ctx := createRequestCtxFromPath("HEAD", "/account/1")
+			router.Handler(ctx)
+
+			Expect(value1).To(Equal(2))
+			Expect(ctx.Response.SkipBody).To(BeFalse())
+		})
+
+		It("should not serve HEAD with the GET handler when disabled", func() {
+			value1 := 1
+			router.GET("/account/:id", func(ctx *fasthttp.RequestCtx) {
+				Fail("should not be called")
+			})
+			router.HandleHEAD = false
+			router.MethodNotAllowed = func(ctx *fasthttp.RequestCtx) {
+				Expect(string(ctx.Response.Header.Peek("Allow"))).To(Equal("GET"))
+				value1 = 2
+			}
+
+			ctx := createRequestCtxFromPath("HEAD", "/account/1")
+			router.Handler(ctx)
+
+			Expect(value1).To(Equal(2))
+			Expect(ctx.Response.SkipBody).To(BeFalse())
+		})
+
+		It("should call the not found callback for HEAD of an unknown path", func() {
+			value1 := 1
+			router.GET("/account/:id", func(ctx *fasthttp.RequestCtx) {
+				Fail("should not be called")
+			})
+			router.NotFound = func(ctx *fasthttp.RequestCtx) {
+				Expect(ctx.Response.SkipBody).To(BeFalse())
+				value1 = 2
+			}
+
+			router.Handler(createRequestCtxFromPath("HEAD", "/profile/1"))
+
+			Expect(value1).To(Equal(2))
+		})
+
+		It("should resolve a custom method", func() {
+			value1 := 1
+			router.Handle("PROPFIND", "/files/:name", func(ctx *fasthttp.RequestCtx) {
+				Expect(ctx.UserValue("name")).To(Equal("file1"))
+				value1 = 2
+			})
+
+			router.Handler(createRequestCtxFromPath("PROPFIND", "/files/file1"))
+
+			Expect(value1).To(Equal(2))
+		})
+
+		It("should resolve any method", func() {
+			value1 := 0
+			router.Any("/files/:name", func(ctx *fasthttp.RequestCtx) {
+				value1++
+			})
+			router.NotFound = func(ctx *fasthttp.RequestCtx) {
+				Fail("should not be called")
+			}
+
+			router.Handler(createRequestCtxFromPath("GET", "/files/file1"))
+			router.Handler(createRequestCtxFromPath("TRACE", "/files/file1"))
+			router.Handler(createRequestCtxFromPath("CONNECT", "/files/file1"))
+
+			Expect(value1).To(Equal(3))
+		})
+
+		It("should resolve routes with and without trailing slash apart", func() {
+			value1 := 1
+			value2 := 1
+			router.GET("/account", func(ctx *fasthttp.RequestCtx) {
+				value1 = 2
+			})
+			router.GET("/account/", func(ctx *fasthttp.RequestCtx) {
+				value2 = 2
+			})
+
+			router.Handler(createRequestCtxFromPath("GET", "/account"))
+			Expect(value1).To(Equal(2))
+			Expect(value2).To(Equal(1))
+
+			router.Handler(createRequestCtxFromPath("GET", "/account/"))
+			Expect(value2).To(Equal(2))
+		})
+
+		It("should redirect adding the trailing slash", func() {
+			router.GET("/account/:id/", func(ctx *fasthttp.RequestCtx) {
+				Fail("should not be called")
+			})
+			router.RedirectTrailingSlash = true
+
+			ctx := createRequestCtxFromPath("GET", "/account/1")
+			router.Handler(ctx)
+
+			Expect(ctx.Response.StatusCode()).To(Equal(fasthttp.StatusMovedPermanently))
+			Expect(string(ctx.Response.Header.Peek("Location"))).To(Equal("/account/1/"))
+		})
+
+		It("should redirect removing the trailing slash", func() {
+			router.POST("/account/:id", func(ctx *fasthttp.RequestCtx) {
+				Fail("should not be called")
+			})
+			router.RedirectTrailingSlash = true
+
+			ctx := createRequestCtxFromPath("POST", "/account/1/")
+			router.Handler(ctx)
+
+			Expect(ctx.Response.StatusCode()).To(Equal(fasthttp.StatusPermanentRedirect))
+			Expect(string(ctx.Response.Header.Peek("Location"))).To(Equal("/account/1"))
+		})
+
+		It("should not redirect the trailing slash to another host", func() {
+			router.GET("/:name", emptyHandler)
+			router.GET("/:a/:b/", emptyHandler)
+			router.RedirectTrailingSlash = true
+
+			ctx := createRequestCtxFromPath("GET", "//evil.com/")
+			ctx.Request.Header.SetHost("example.com")
+			router.Handler(ctx)
+			Expect(ctx.Response.StatusCode()).To(Equal(fasthttp.StatusMovedPermanently))
+			Expect(string(ctx.Response.Header.Peek("Location"))).To(Equal("/evil.com"))
+
+			ctx = createRequestCtxFromPath("GET", "//evil.com/x")
+			ctx.Request.Header.SetHost("example.com")
+			router.Handler(ctx)
+			Expect(ctx.Response.StatusCode()).To(Equal(fasthttp.StatusMovedPermanently))
+			Expect(string(ctx.Response.Header.Peek("Location"))).To(Equal("/evil.com/x/"))
+		})
+
+		It("should redirect keeping the query string", func() {
+			router.GET("/account", emptyHandler)
+			router.RedirectTrailingSlash = true
+
+			ctx := createRequestCtxFromPath("GET", "/account/")
+			ctx.Request.URI().SetQueryString("page=2&sort=name")
+			router.Handler(ctx)
+
+			Expect(ctx.Response.StatusCode()).To(Equal(fasthttp.StatusMovedPermanently))
+			Expect(string(ctx.Response.Header.Peek("Location"))).To(Equal("/account?page=2&sort=name"))
+		})
+
+		It("should not redirect the trailing slash when disabled", func() {
+			value1 := 1
+			router.GET("/account", emptyHandler)
+			router.NotFound = func(ctx *fasthttp.RequestCtx) {
+				value1 = 2
+			}
+
+			ctx := createRequestCtxFromPath("GET", "/account/")
+			router.Handler(ctx)
+
+			Expect(value1).To(Equal(2))
+			Expect(ctx.Response.Header.Peek("Location")).To(BeEmpty())
+		})
+
+		It("should redirect to the fixed path", func() {
+			router.GET("/account/:id/profile", func(ctx *fasthttp.RequestCtx) {
+				Fail("should not be called")
+			})
+			router.RedirectFixedPath = true
+
+			ctx := createRequestCtxFromPath("GET", "/account//1/./history/../profile")
+			ctx.Request.URI().SetQueryString("a=1")
+			router.Handler(ctx)
+
+			Expect(ctx.Response.StatusCode()).To(Equal(fasthttp.StatusMovedPermanently))
+			Expect(string(ctx.Response.Header.Peek("Location"))).To(Equal("/account/1/profile?a=1"))
+		})
+
+		It("should redirect to the fixed path with the trailing slash toggled", func() {
+			router.PUT("/account/:id", emptyHandler)
+			router.RedirectFixedPath = true
+			router.RedirectTrailingSlash = true
+
+			ctx := createRequestCtxFromPath("PUT", "/account//1/")
+			router.Handler(ctx)
+
+			Expect(ctx.Response.StatusCode()).To(Equal(fasthttp.StatusPermanentRedirect))
+			Expect(string(ctx.Response.Header.Peek("Location"))).To(Equal("/account/1"))
+		})
+
+		It("should serve non canonical paths when the fixed path redirect is disabled", func() {
+			value1 := 1
+			router.GET("/account/:id", func(ctx *fasthttp.RequestCtx) {
+				value1 = 2
+			})
+
+			router.Handler(createRequestCtxFromPath("GET", "/account//1"))
+
+			Expect(value1).To(Equal(2))
+		})
+
+		It("should not resolve routes with a different case by default", func() {
+			value1 := 1
+			router.GET("/orders/:id", func(ctx *fasthttp.RequestCtx) {
+				Fail("should not be called")
+			})
+			router.NotFound = func(ctx *fasthttp.RequestCtx) {
+				value1 = 2
+			}
+
+			router.Handler(createRequestCtxFromPath("GET", "/ORDERS/123"))
+
+			Expect(value1).To(Equal(2))
+		})
+
+		It("should resolve routes ignoring the case", func() {
+			value1 := 0
+			router.GET("/orders/:id/Items", func(ctx *fasthttp.RequestCtx) {
+				Expect(ctx.UserValue("id")).To(Equal("AbC"))
+				value1++
+			})
+			router.CaseInsensitive = true
+
+			router.Handler(createRequestCtxFromPath("GET", "/orders/AbC/Items"))
+			router.Handler(createRequestCtxFromPath("GET", "/Orders/AbC/items"))
+			router.Handler(createRequestCtxFromPath("GET", "/ORDERS/AbC/ITEMS"))
+
+			Expect(value1).To(Equal(3))
+		})
+
+		It("should prefer the exact case when resolving ignoring the case", func() {
+			value1 := 1
+			value2 := 1
+			router.GET("/orders/:id", func(ctx *fasthttp.RequestCtx) {
+				Expect(ctx.UserValue("id")).To(Equal("New"))
+				value1 = 2
+			})
+			router.GET("/orders/new", func(ctx *fasthttp.RequestCtx) {
+				value2 = 2
+			})
+			router.CaseInsensitive = true
+
+			router.Handler(createRequestCtxFromPath("GET", "/orders/new"))
+			Expect(value2).To(Equal(2))
+
+			value2 = 1
+			router.Handler(createRequestCtxFromPath("GET", "/ORDERS/NEW"))
+			Expect(value2).To(Equal(2))
+			Expect(value1).To(Equal(1))
+		})
+
+		It("should not allocate when resolving ignoring the case", func() {
+			if raceEnabled {
+				Skip("the race detector makes sync.Pool allocate")
+			}
+			router.GET("/orders/:id/items", emptyHandler)
+			path := []byte("ORDERS/123/Items")
+			values := make([][]byte, 0, 4)
+
+			Expect(testing.AllocsPerRun(100, func() {
+				router.tree("GET").Matches(path, values[0:0], true)
+			})).To(BeZero())
+		})
+
+		It("should redirect to the registered case", func() {
+			router.GET("/orders/:id/items", func(ctx *fasthttp.RequestCtx) {
+				Fail("should not be called")
+			})
+			router.RedirectFixedCase = true
+
+			ctx := createRequestCtxFromPath("GET", "/Orders/AbC/ITEMS")
+			ctx.Request.URI().SetQueryString("a=1")
+			router.Handler(ctx)
+
+			Expect(ctx.Response.StatusCode()).To(Equal(fasthttp.StatusMovedPermanently))
+			Expect(string(ctx.Response.Header.Peek("Location"))).To(Equal("/orders/AbC/items?a=1"))
+		})
+
+		It("should redirect to the registered case keeping the catch-all", func() {
+			router.POST("/Static/*filepath", emptyHandler)
+			router.RedirectFixedCase = true
+
+			ctx := createRequestCtxFromPath("POST", "/static/CSS/a b.css")
+			router.Handler(ctx)
+
+			Expect(ctx.Response.StatusCode()).To(Equal(fasthttp.StatusPermanentRedirect))
+			Expect(string(ctx.Response.Header.Peek("Location"))).To(Equal("/Static/CSS/a%20b.css"))
+		})
+
+		It("should redirect to the registered case keeping the raw path escaped", func() {
+			router.GET("/Orders/:id", emptyHandler)
+			router.GET("/Files/*filepath", emptyHandler)
+			router.RedirectFixedCase = true
+			router.UseRawPath = true
+
+			ctx := createRequestCtxFromPath("GET", "/orders/a%2Fb")
+			router.Handler(ctx)
+			Expect(ctx.Response.StatusCode()).To(Equal(fasthttp.StatusMovedPermanently))
+			Expect(string(ctx.Response.Header.Peek("Location"))).To(Equal("/Orders/a%2Fb"))
+
+			ctx = createRequestCtxFromPath("GET", "/files/a%20b/c%2Fd")
+			router.Handler(ctx)
+			Expect(string(ctx.Response.Header.Peek("Location"))).To(Equal("/Files/a%20b/c%2Fd"))
+		})
+
+		It("should split encoded slashes by default", func() {
+			value1 := 1
+			router.GET("/objects/:key", func(ctx *fasthttp.RequestCtx) {
+				Fail("should not be called")
+			})
+			router.NotFound = func(ctx *fasthttp.RequestCtx) {
+				value1 = 2
+			}
+
+			router.Handler(createRequestCtxFromPath("GET", "/objects/photos%2F2017%2Fa.jpg"))
+
+			Expect(value1).To(Equal(2))
+		})
+
+		It("should resolve routes on the raw path", func() {
+			value1 := 1
+			router.GET("/buckets/:bucket/objects/:key", func(ctx *fasthttp.RequestCtx) {
+				Expect(ctx.UserValue("bucket")).To(Equal("my bucket"))
+				Expect(ctx.UserValue("key")).To(Equal("photos/2017/a.jpg"))
+				value1 = 2
+			})
+			router.UseRawPath = true
+
+			router.Handler(createRequestCtxFromPath("GET", "/buckets/my%20bucket/objects/photos%2F2017%2Fa.jpg"))
+
+			Expect(value1).To(Equal(2))
+		})
+
+		It("should resolve a catch-all route on the raw path", func() {
+			value1 := 1
+			router.GET("/files/*filepath", func(ctx *fasthttp.RequestCtx) {
+				Expect(ctx.UserValue("filepath")).To(Equal("a/b%/c d"))
+				value1 = 2
+			})
+			router.UseRawPath = true
+
+			router.Handler(createRequestCtxFromPath("GET", "/files/a/b%25/c%20d"))
+
+			Expect(value1).To(Equal(2))
+		})
+
+		It("should keep malformed values on the raw path", func() {
+			value1 := 1
+			router.GET("/objects/:key", func(ctx *fasthttp.RequestCtx) {
+				Expect(ctx.UserValue("key")).To(Equal("100%"))
+				value1 = 2
+			})
+			router.UseRawPath = true
+
+			router.Handler(createRequestCtxFromPath("GET", "/objects/100%"))
+
+			Expect(value1).To(Equal(2))
+		})
+
+		It("should expose the params", func() {
+			value1 := 1
+			router.GET("/:account/transactions/:id", func(ctx *fasthttp.RequestCtx) {
+				params := GetParams(ctx)
+				Expect(params).To(HaveLen(2))
+				Expect(params.ByName("account")).To(Equal([]byte("account1")))
+				Expect(params.ByName("id")).To(Equal([]byte("10")))
+				Expect(params.ByName("unknown")).To(BeNil())
+				Expect(params.Get(0)).To(Equal([]byte("account1")))
+				Expect(params.Get(1)).To(Equal([]byte("10")))
+				Expect(params.Get(2)).To(BeNil())
+				Expect(params[1].Key).To(Equal("id"))
+				Expect(ctx.UserValue("account")).To(Equal("account1"))
+				value1 = 2
+			})
+
+			router.Handler(createRequestCtxFromPath("GET", "/account1/transactions/10"))
+
+			Expect(value1).To(Equal(2))
+		})
+
+		It("should expose the decoded params on the raw path", func() {
+			value1 := 1
+			router.GET("/objects/:key/*rest", func(ctx *fasthttp.RequestCtx) {
+				params := GetParams(ctx)
+				Expect(params.ByName("key")).To(Equal([]byte("a/b")))
+				Expect(params.ByName("rest")).To(Equal([]byte("c d/e")))
+				value1 = 2
+			})
+			router.UseRawPath = true
+
+			router.Handler(createRequestCtxFromPath("GET", "/objects/a%2Fb/c%20d/e"))
+
+			Expect(value1).To(Equal(2))
+		})
+
+		It("should not expose the params once the request is handled", func() {
+			var after Params
+			router.Use(func(next fasthttp.RequestHandler) fasthttp.RequestHandler {
+				return func(ctx *fasthttp.RequestCtx) {
+					next(ctx)
+					after = GetParams(ctx)
+				}
+			})
+			router.GET("/orders/:id", emptyHandler)
+
+			first := createRequestCtxFromPath("GET", "/orders/first")
+			router.Handler(first)
+			Expect(after).To(BeNil())
+			router.Handler(createRequestCtxFromPath("GET", "/orders/second"))
+			Expect(GetParams(first)).To(BeNil())
+		})
+
+		It("should not set user values when disabled", func() {
+			value1 := 1
+			router.GET("/:account", func(ctx *fasthttp.RequestCtx) {
+				Expect(ctx.UserValue("account")).To(BeNil())
+				Expect(GetParams(ctx).ByName("account")).To(Equal([]byte("account1")))
+				value1 = 2
+			})
+			router.UserValues = false
+
+			router.Handler(createRequestCtxFromPath("GET", "/account1"))
+
+			Expect(value1).To(Equal(2))
+		})
+
+		It("should not allocate when resolving routes", func() {
+			if raceEnabled {
+				Skip("the race detector makes sync.Pool allocate")
+			}
+			router.GET("/", emptyHandler)
+			router.GET("/static/path", emptyHandler)
+			router.GET("/accounts/:account/transactions/:id", emptyHandler)
+			router.GET("/files/*filepath", emptyHandler)
+			router.UserValues = false
+
+			for _, path := range []string{"/", "/static/path", "/accounts/account1/transactions/10", "/files/a/b/c"} {
+				ctx := createRequestCtxFromPath("GET", path)
+				Expect(testing.AllocsPerRun(100, func() {
+					router.Handler(ctx)
+				})).To(BeZero(), path)
+			}
+		})
+
+		It("should not allocate when the fixed path redirect is enabled", func() {
+			if raceEnabled {
+				Skip("the race detector makes sync.Pool allocate")
+			}
+			router.GET("/accounts/:account/transactions/:id", emptyHandler)
+			router.RedirectFixedPath = true
+			router.UserValues = false
+
+			ctx := createRequestCtxFromPath("GET", "/accounts/account1/transactions/.10")
+			Expect(testing.AllocsPerRun(100, func() {
+				router.Handler(ctx)
+			})).To(BeZero())
+		})
+
+		It("should not allocate when resolving any method", func() {
+			if raceEnabled {
+				Skip("the race detector makes sync.Pool allocate")
+			}
+			methods := []string{"GET", "HEAD", "POST", "PUT", "PATCH", "DELETE", "OPTIONS", "CONNECT", "TRACE", "PROPFIND"}
+			for _, method := range methods {
+				router.Handle(method, "/accounts/:account", emptyHandler)
+			}
+			router.UserValues = false
+
+			for _, method := range methods {
+				ctx := createRequestCtxFromPath(method, "/accounts/account1")
+				Expect(testing.AllocsPerRun(100, func() {
+					router.Handler(ctx)
+				})).To(BeZero(), method)
+			}
+		})
+
+		It("should resolve the standard and custom methods apart", func() {
+			value1 := 1
+			value2 := 1
+			router.GET("/accounts/:account", func(ctx *fasthttp.RequestCtx) {
+				value1 = 2
+			})
+			router.Handle("PROPFIND", "/accounts/:account", func(ctx *fasthttp.RequestCtx) {
+				value2 = 2
+			})
+
+			router.Handler(createRequestCtxFromPath("PROPFIND", "/accounts/account1"))
+			Expect(value1).To(Equal(1))
+			Expect(value2).To(Equal(2))
+
+			router.Handler(createRequestCtxFromPath("GET", "/accounts/account1"))
+			Expect(value1).To(Equal(2))
+		})
+
+		It("should list the custom methods in the allow header", func() {
+			router.GET("/accounts/:account", emptyHandler)
+			router.Handle("PROPFIND", "/accounts/:account", emptyHandler)
+
+			ctx := createRequestCtxFromPath("POST", "/accounts/account1")
+			router.Handler(ctx)
+
+			Expect(ctx.Response.StatusCode()).To(Equal(fasthttp.StatusMethodNotAllowed))
+			Expect(string(ctx.Response.Header.Peek("Allow"))).To(Equal("GET, HEAD, PROPFIND"))
+		})
+
+		It("should resolve constrained wildcards in registration order", func() {
+			var called string
+			router.GET("/users/:id{[0-9]+}", func(ctx *fasthttp.RequestCtx) {
+				called = "id:" + string(GetParams(ctx).ByName("id"))
+			})
+			router.GET("/users/:slug{[a-z-]+}", func(ctx *fasthttp.RequestCtx) {
+				called = "slug:" + string(GetParams(ctx).ByName("slug"))
+			})
+			router.GET("/users/:any{.+}", func(ctx *fasthttp.RequestCtx) {
+				called = "any:" + string(GetParams(ctx).ByName("any"))
+			})
+
+			router.Handler(createRequestCtxFromPath("GET", "/users/42"))
+			Expect(called).To(Equal("id:42"))
+			router.Handler(createRequestCtxFromPath("GET", "/users/john-doe"))
+			Expect(called).To(Equal("slug:john-doe"))
+			router.Handler(createRequestCtxFromPath("GET", "/users/John_42"))
+			Expect(called).To(Equal("any:John_42"))
+		})
+
+		It("should prefer constrained wildcards to the wildcard", func() {
+			var called string
+			router.GET("/users/:name", func(ctx *fasthttp.RequestCtx) {
+				called = "name"
+			})
+			router.GET("/users/:id{[0-9]+}", func(ctx *fasthttp.RequestCtx) {
+				called = "id"
+			})
+
+			router.Handler(createRequestCtxFromPath("GET", "/users/42"))
+			Expect(called).To(Equal("id"))
+			router.Handler(createRequestCtxFromPath("GET", "/users/john"))
+			Expect(called).To(Equal("name"))
+		})
+
+		It("should backtrack from a constrained wildcard", func() {
+			var called string
+			router.GET("/users/:id{[0-9]+}/posts", func(ctx *fasthttp.RequestCtx) {
+				called = "posts"
+			})
+			router.GET("/users/:name/profile", func(ctx *fasthttp.RequestCtx) {
+				called = "profile:" + string(GetParams(ctx).ByName("name"))
+			})
+
+			router.Handler(createRequestCtxFromPath("GET", "/users/42/profile"))
+			Expect(called).To(Equal("profile:42"))
+		})
+
+		It("should call the not found callback when no constraint matches", func() {
+			notFound := false
+			router.NotFound = func(ctx *fasthttp.RequestCtx) {
+				notFound = true
+			}
+			router.GET("/users/:id{[0-9]+}", emptyHandler)
+
+			router.Handler(createRequestCtxFromPath("GET", "/users/john"))
+			Expect(notFound).To(BeTrue())
+		})
+
+		It("should answer 405 only for the paths matching a constraint", func() {
+			router.GET("/users/:id{[0-9]+}", emptyHandler)
+			router.POST("/users/:slug{[a-z]+}", emptyHandler)
+
+			ctx := createRequestCtxFromPath("DELETE", "/users/42")
+			router.Handler(ctx)
+			Expect(ctx.Response.StatusCode()).To(Equal(fasthttp.StatusMethodNotAllowed))
+			Expect(string(ctx.Response.Header.Peek("Allow"))).To(Equal("GET, HEAD"))
+
+			ctx = createRequestCtxFromPath("POST", "/users/42")
+			router.Handler(ctx)
+			Expect(ctx.Response.StatusCode()).To(Equal(fasthttp.StatusMethodNotAllowed))
+			Expect(string(ctx.Response.Header.Peek("Allow"))).To(Equal("GET, HEAD"))
+
+			notFound := false
+			router.NotFound = func(ctx *fasthttp.RequestCtx) {
+				notFound = true
+			}
+			router.Handler(createRequestCtxFromPath("DELETE", "/users/4-2"))
+			Expect(notFound).To(BeTrue())
+		})
+
+		It("should resolve typed wildcards", func() {
+			var called string
+			router.GET("/files/:id<int>", func(ctx *fasthttp.RequestCtx) {
+				called = "int:" + string(GetParams(ctx).ByName("id"))
+			})
+			router.GET("/files/:ref<uuid>", func(ctx *fasthttp.RequestCtx) {
+				called = "uuid:" + string(GetParams(ctx).ByName("ref"))
+			})
+			router.GET("/files/:kind<alpha>", func(ctx *fasthttp.RequestCtx) {
+				called = "alpha:" + string(GetParams(ctx).ByName("kind"))
+			})
+			router.GET("/files/:name", func(ctx *fasthttp.RequestCtx) {
+				called = "name:" + string(GetParams(ctx).ByName("name"))
+			})
+
+			router.Handler(createRequestCtxFromPath("GET", "/files/-42"))
+			Expect(called).To(Equal("int:-42"))
+			router.Handler(createRequestCtxFromPath("GET", "/files/123E4567-e89b-12d3-a456-426614174000"))
+			Expect(called).To(Equal("uuid:123E4567-e89b-12d3-a456-426614174000"))
+			router.Handler(createRequestCtxFromPath("GET", "/files/Report"))
+			Expect(called).To(Equal("alpha:Report"))
+			router.Handler(createRequestCtxFromPath("GET", "/files/report.pdf"))
+			Expect(called).To(Equal("name:report.pdf"))
+			router.Handler(createRequestCtxFromPath("GET", "/files/-"))
+			Expect(called).To(Equal("name:-"))
+		})
+
+		It("should not call the typed handler for invalid values", func() {
+			called := false
+			notFound := false
+			router.GET("/orders/:id<int>", func(ctx *fasthttp.RequestCtx) {
+				called = true
+			})
+			router.NotFound = func(ctx *fasthttp.RequestCtx) {
+				notFound = true
+			}
+
+			router.Handler(createRequestCtxFromPath("GET", "/orders/abc"))
+			Expect(called).To(BeFalse())
+			Expect(notFound).To(BeTrue())
+		})
+
+		It("should resolve registered parameter types", func() {
+			var called string
+			router.RegisterParamType("hex", func(value []byte) bool {
+				for _, c := range value {
+					if !isHex(c) {
+						return false
+					}
+				}
+				return len(value) > 0
+			})
+			router.GET("/commits/:sha<hex>", func(ctx *fasthttp.RequestCtx) {
+				called = string(GetParams(ctx).ByName("sha"))
+			})
+			router.Group("/repos").GET("/:repo/commits/:sha<hex>", func(ctx *fasthttp.RequestCtx) {
+				called = string(GetParams(ctx).ByName("repo")) + "@" + string(GetParams(ctx).ByName("sha"))
+			})
+
+			router.Handler(createRequestCtxFromPath("GET", "/commits/beef"))
+			Expect(called).To(Equal("beef"))
+			router.Handler(createRequestCtxFromPath("GET", "/repos/router/commits/a1b2"))
+			Expect(called).To(Equal("router@a1b2"))
+
+			called = ""
+			router.Handler(createRequestCtxFromPath("GET", "/commits/master"))
+			Expect(called).To(BeEmpty())
+		})
+
+		It("should replace the built-in parameter types", func() {
+			called := false
+			router.RegisterParamType("int", func(value []byte) bool {
+				return len(value) > 0 && value[0] != '-' && isInt(value)
+			})
+			router.GET("/pages/:page<int>", func(ctx *fasthttp.RequestCtx) {
+				called = true
+			})
+
+			router.Handler(createRequestCtxFromPath("GET", "/pages/-1"))
+			Expect(called).To(BeFalse())
+			router.Handler(createRequestCtxFromPath("GET", "/pages/1"))
+			Expect(called).To(BeTrue())
+		})
+
+		It("should resolve wildcards in the middle of a segment", func() {
+			var called string
+			router.GET("/files/:name.:ext", func(ctx *fasthttp.RequestCtx) {
+				called = string(GetParams(ctx).ByName("name")) + "|" + string(GetParams(ctx).ByName("ext"))
+			})
+			router.GET("/reports/report-:year<int>.csv", func(ctx *fasthttp.RequestCtx) {
+				called = "report:" + string(GetParams(ctx).ByName("year"))
+			})
+			router.GET("/api/v:major/users", func(ctx *fasthttp.RequestCtx) {
+				called = "v" + string(GetParams(ctx).ByName("major"))
+			})
+
+			router.Handler(createRequestCtxFromPath("GET", "/files/photo.png"))
+			Expect(called).To(Equal("photo|png"))
+			router.Handler(createRequestCtxFromPath("GET", "/files/archive.tar.gz"))
+			Expect(called).To(Equal("archive|tar.gz"))
+			router.Handler(createRequestCtxFromPath("GET", "/reports/report-2020.csv"))
+			Expect(called).To(Equal("report:2020"))
+			router.Handler(createRequestCtxFromPath("GET", "/api/v2/users"))
+			Expect(called).To(Equal("v2"))
+		})
+
+		It("should backtrack over the ends of a mid-segment wildcard", func() {
+			var called string
+			router.GET("/files/:name.json", func(ctx *fasthttp.RequestCtx) {
+				called = "json:" + string(GetParams(ctx).ByName("name"))
+			})
+			router.GET("/files/:name", func(ctx *fasthttp.RequestCtx) {
+				called = "name:" + string(GetParams(ctx).ByName("name"))
+			})
+
+			router.Handler(createRequestCtxFromPath("GET", "/files/data.v1.json"))
+			Expect(called).To(Equal("json:data.v1"))
+			router.Handler(createRequestCtxFromPath("GET", "/files/data.v1.xml"))
+			Expect(called).To(Equal("name:data.v1.xml"))
+			router.Handler(createRequestCtxFromPath("GET", "/files/.json"))
+			Expect(called).To(Equal("name:.json"))
+		})
+
+		It("should prefer static routes to mid-segment wildcards", func() {
+			var called string
+			router.GET("/files/:name.json", func(ctx *fasthttp.RequestCtx) {
+				called = "wildcard"
+			})
+			router.GET("/files/index.json", func(ctx *fasthttp.RequestCtx) {
+				called = "static"
+			})
+
+			router.Handler(createRequestCtxFromPath("GET", "/files/index.json"))
+			Expect(called).To(Equal("static"))
+			router.Handler(createRequestCtxFromPath("GET", "/files/other.json"))
+			Expect(called).To(Equal("wildcard"))
+		})
+
+		It("should call the not found callback when a mid-segment literal is missing", func() {
+			notFound := false
+			router.NotFound = func(ctx *fasthttp.RequestCtx) {
+				notFound = true
+			}
+			router.GET("/reports/report-:year<int>.csv", emptyHandler)
+
+			router.Handler(createRequestCtxFromPath("GET", "/reports/report-2020.pdf"))
+			Expect(notFound).To(BeTrue())
+
+			notFound = false
+			router.Handler(createRequestCtxFromPath("GET", "/reports/report-abc.csv"))
+			Expect(notFound).To(BeTrue())
+		})
+
+		It("should resolve mid-segment wildcards ignoring the case of the literals", func() {
+			var called string
+			router.CaseInsensitive = true
+			router.GET("/files/:name.JSON", func(ctx *fasthttp.RequestCtx) {
+				called = string(GetParams(ctx).ByName("name"))
+			})
+
+			router.Handler(createRequestCtxFromPath("GET", "/FILES/Data.json"))
+			Expect(called).To(Equal("Data"))
+		})
+
+		It("should resolve optional segments", func() {
+			var called string
+			router.GET("/posts/:page?", func(ctx *fasthttp.RequestCtx) {
+				called = "posts:" + string(GetParams(ctx).ByName("page"))
+			})
+			router.Group("/docs").GET("/(:lang)/intro", func(ctx *fasthttp.RequestCtx) {
+				called = "intro:" + string(GetParams(ctx).ByName("lang"))
+			})
+
+			router.Handler(createRequestCtxFromPath("GET", "/posts"))
+			Expect(called).To(Equal("posts:"))
+			router.Handler(createRequestCtxFromPath("GET", "/posts/2"))
+			Expect(called).To(Equal("posts:2"))
+			router.Handler(createRequestCtxFromPath("GET", "/docs/intro"))
+			Expect(called).To(Equal("intro:"))
+			router.Handler(createRequestCtxFromPath("GET", "/docs/pt-br/intro"))
+			Expect(called).To(Equal("intro:pt-br"))
+		})
+
+		It("should list the methods of optional variants in the allow header", func() {
+			router.GET("/posts/:page?", emptyHandler)
+			router.DELETE("/posts", emptyHandler)
+
+			ctx := createRequestCtxFromPath("POST", "/posts")
+			router.Handler(ctx)
+			Expect(ctx.Response.StatusCode()).To(Equal(fasthttp.StatusMethodNotAllowed))
+			Expect(string(ctx.Response.Header.Peek("Allow"))).To(Equal("DELETE, GET, HEAD"))
+
+			ctx = createRequestCtxFromPath("POST", "/posts/2")
+			router.Handler(ctx)
+			Expect(string(ctx.Response.Header.Peek("Allow"))).To(Equal("GET, HEAD"))
+		})
+
+		It("should resolve host routes", func() {
+			var called string
+			router.GET("/users", func(ctx *fasthttp.RequestCtx) {
+				called = "default"
+			})
+			router.Host("api.example.com").GET("/users", func(ctx *fasthttp.RequestCtx) {
+				called = "api"
+			})
+			router.Host("Admin.Example.com").GET("/users", func(ctx *fasthttp.RequestCtx) {
+				called = "admin"
+			})
+
+			ctx := createRequestCtxFromPath("GET", "/users")
+			ctx.Request.URI().SetHost("api.example.com")
+			router.Handler(ctx)
+			Expect(called).To(Equal("api"))
+
+			ctx = createRequestCtxFromPath("GET", "/users")
+			ctx.Request.URI().SetHost("ADMIN.example.com:8080")
+			router.Handler(ctx)
+			Expect(called).To(Equal("admin"))
+
+			ctx = createRequestCtxFromPath("GET", "/users")
+			ctx.Request.URI().SetHost("www.example.com")
+			router.Handler(ctx)
+			Expect(called).To(Equal("default"))
+		})
+
+		It("should capture the labels of host routes as parameters", func() {
+			var params Params
+			var tenant interface{}
+			router.Host(":tenant.example.com").GET("/projects/:id", func(ctx *fasthttp.RequestCtx) {
+				params = append(Params(nil), GetParams(ctx)...)
+				tenant = ctx.UserValue("tenant")
+			})
+
+			ctx := createRequestCtxFromPath("GET", "/projects/42")
+			ctx.Request.URI().SetHost("acme.example.com")
+			router.Handler(ctx)
+			Expect(params).To(Equal(Params{
+				{Key: "tenant", Value: []byte("acme")},
+				{Key: "id", Value: []byte("42")},
+			}))
+			Expect(tenant).To(Equal("acme"))
+		})
+
+		It("should keep the case of the host pattern", func() {
+			var tenant interface{}
+			var param []byte
+			router.Host(":tenantID.Example.com").GET("/", func(ctx *fasthttp.RequestCtx) {
+				tenant = ctx.UserValue("tenantID")
+				param = append([]byte(nil), GetParams(ctx).ByName("tenantID")...)
+			})
+			Expect(router.Host(":tenantID.example.COM")).To(BeIdenticalTo(router.Host(":tenantID.Example.com")))
+
+			ctx := createRequestCtxFromPath("GET", "/")
+			ctx.Request.URI().SetHost("acme.example.com")
+			router.Handler(ctx)
+			Expect(tenant).To(Equal("acme"))
+			Expect(param).To(Equal([]byte("acme")))
+			Expect(router.Routes()[0].Host).To(Equal(":tenantID.Example.com"))
+		})
+
+		It("should try the host routes in registration order", func() {
+			var called string
+			router.Host("api.example.com").GET("/status", func(ctx *fasthttp.RequestCtx) {
+				called = "api"
+			})
+			router.Host(":tenant.example.com").GET("/users", func(ctx *fasthttp.RequestCtx) {
+				called = "tenant:" + string(GetParams(ctx).ByName("tenant"))
+			})
+
+			ctx := createRequestCtxFromPath("GET", "/users")
+			ctx.Request.URI().SetHost("api.example.com")
+			router.Handler(ctx)
+			Expect(called).To(Equal("tenant:api"))
+
+			ctx = createRequestCtxFromPath("GET", "/status")
+			ctx.Request.URI().SetHost("api.example.com")
+			router.Handler(ctx)
+			Expect(called).To(Equal("api"))
+		})
+
+		It("should not match hosts with a different number of labels", func() {
+			notFound := false
+			router.NotFound = func(ctx *fasthttp.RequestCtx) {
+				notFound = true
+			}
+			router.Host(":tenant.example.com").GET("/", emptyHandler)
+
+			for _, host := range []string{"example.com", "a.b.example.com", ".example.com"} {
+				notFound = false
+				ctx := createRequestCtxFromPath("GET", "/")
+				ctx.Request.URI().SetHost(host)
+				router.Handler(ctx)
+				Expect(notFound).To(BeTrue(), host)
+			}
+		})
+
+		It("should fall back to the router when the host route does not match", func() {
+			notFound := false
+			router.NotFound = func(ctx *fasthttp.RequestCtx) {
+				notFound = true
+			}
+			router.Host("api.example.com").GET("/users", emptyHandler)
+			router.POST("/users", emptyHandler)
+
+			ctx := createRequestCtxFromPath("GET", "/other")
+			ctx.Request.URI().SetHost("api.example.com")
+			router.Handler(ctx)
+			Expect(notFound).To(BeTrue())
+
+			ctx = createRequestCtxFromPath("DELETE", "/users")
+			ctx.Request.URI().SetHost("api.example.com")
+			router.Handler(ctx)
+			Expect(ctx.Response.StatusCode()).To(Equal(fasthttp.StatusMethodNotAllowed))
+			Expect(string(ctx.Response.Header.Peek("Allow"))).To(Equal("GET, HEAD, POST"))
+		})
+
+		It("should serve HEAD requests with the GET handler of host routes", func() {
+			called := false
+			router.Host("api.example.com").GET("/users", func(ctx *fasthttp.RequestCtx) {
+				called = true
+			})
+
+			ctx := createRequestCtxFromPath("HEAD", "/users")
+			ctx.Request.URI().SetHost("api.example.com")
+			router.Handler(ctx)
+			Expect(called).To(BeTrue())
+			Expect(ctx.Response.SkipBody).To(BeTrue())
+		})
+
+		It("should answer the method not allowed and OPTIONS requests of host routes", func() {
+			router.Host("api.example.com").GET("/x", emptyHandler)
+			router.HandleOPTIONS = true
+
+			ctx := createRequestCtxFromPath("POST", "/x")
+			ctx.Request.URI().SetHost("api.example.com")
+			router.Handler(ctx)
+			Expect(ctx.Response.StatusCode()).To(Equal(fasthttp.StatusMethodNotAllowed))
+			Expect(string(ctx.Response.Header.Peek("Allow"))).To(Equal("GET, HEAD, OPTIONS"))
+
+			ctx = createRequestCtxFromPath("OPTIONS", "/x")
+			ctx.Request.URI().SetHost("api.example.com")
+			router.Handler(ctx)
+			Expect(ctx.Response.StatusCode()).To(Equal(fasthttp.StatusOK))
+			Expect(string(ctx.Response.Header.Peek("Allow"))).To(Equal("GET, HEAD, OPTIONS"))
+
+			notFound := false
+			router.NotFound = func(ctx *fasthttp.RequestCtx) {
+				notFound = true
+			}
+			ctx = createRequestCtxFromPath("POST", "/x")
+			ctx.Request.URI().SetHost("www.example.com")
+			router.Handler(ctx)
+			Expect(notFound).To(BeTrue())
+		})
+
+		It("should redirect the trailing slash of host routes", func() {
+			router.Host("api.example.com").GET("/users/:id", emptyHandler)
+			router.RedirectTrailingSlash = true
+
+			ctx := createRequestCtxFromPath("GET", "/users/1/")
+			ctx.Request.URI().SetHost("api.example.com")
+			router.Handler(ctx)
+			Expect(ctx.Response.StatusCode()).To(Equal(fasthttp.StatusMovedPermanently))
+			Expect(string(ctx.Response.Header.Peek("Location"))).To(Equal("/users/1"))
+		})
+
+		It("should redirect to the registered case of host routes", func() {
+			router.Host("api.example.com").GET("/Orders", emptyHandler)
+			router.RedirectFixedCase = true
+
+			ctx := createRequestCtxFromPath("GET", "/orders")
+			ctx.Request.URI().SetHost("api.example.com")
+			router.Handler(ctx)
+			Expect(ctx.Response.StatusCode()).To(Equal(fasthttp.StatusMovedPermanently))
+			Expect(string(ctx.Response.Header.Peek("Location"))).To(Equal("/Orders"))
+
+			notFound := false
+			router.NotFound = func(ctx *fasthttp.RequestCtx) {
+				notFound = true
+			}
+			ctx = createRequestCtxFromPath("GET", "/orders")
+			ctx.Request.URI().SetHost("www.example.com")
+			router.Handler(ctx)
+			Expect(notFound).To(BeTrue())
+		})
+
+		It("should return the same routes for the same host pattern", func() {
+			Expect(router.Host("api.example.com")).To(BeIdenticalTo(router.Host("API.example.com")))
+			Expect(func() {
+				router.Host("api..example.com")
+			}).To(Panic())
+		})
+
+		It("should not allocate when resolving host routes", func() {
+			if raceEnabled {
+				Skip("the race detector makes sync.Pool allocate")
+			}
+			router.Host(":tenant.example.com").GET("/projects/:id", emptyHandler)
+			router.UserValues = false
+
+			ctx := createRequestCtxFromPath("GET", "/projects/42")
+			ctx.Request.URI().SetHost("acme.example.com:8080")
+			Expect(testing.AllocsPerRun(100, func() {
+				router.Handler(ctx)
+			})).To(BeZero())
+		})
+
+		It("should build the URLs of named routes", func() {
+			router.GET("/users/:id", emptyHandler).Name("user.show")
+			router.GET("/files/*filepath", emptyHandler).Name("files")
+			router.GET("/reports/report-:year<int>.csv", emptyHandler).Name("report")
+			api := router.Group("/api")
+			api.Group("/v1").POST("/orgs/:org/repos/:repo{[a-z]+}", emptyHandler).Name("repo.create")
+			router.Any("/status", emptyHandler).Name("status")
+
+			Expect(router.URL("user.show", "id", "42")).To(Equal("/users/42"))
+			Expect(router.URL("user.show", "id", "a b/c?d")).To(Equal("/users/a%20b%2Fc%3Fd"))
+			Expect(router.URL("files", "filepath", "css/main site.css")).To(Equal("/files/css/main%20site.css"))
+			Expect(router.URL("files", "filepath", "")).To(Equal("/files/"))
+			Expect(router.URL("report", "year", "2020")).To(Equal("/reports/report-2020.csv"))
+			Expect(router.URL("repo.create", "repo", "router", "org", "acme")).To(Equal("/api/v1/orgs/acme/repos/router"))
+			Expect(router.URL("status")).To(Equal("/status"))
+		})
+
+		It("should build the URLs of routes with optional segments", func() {
+			router.GET("/posts/:page?", emptyHandler).Name("posts")
+			router.GET("/docs/(:lang)/intro/", emptyHandler).Name("intro")
+
+			Expect(router.URL("posts")).To(Equal("/posts"))
+			Expect(router.URL("posts", "page", "2")).To(Equal("/posts/2"))
+			Expect(router.URL("intro")).To(Equal("/docs/intro/"))
+			Expect(router.URL("intro", "lang", "pt")).To(Equal("/docs/pt/intro/"))
+		})
+
+		It("should return errors for wrong URL parameters", func() {
+			router.GET("/users/:id/posts/:post", emptyHandler).Name("post")
+
+			_, err := router.URL("unknown")
+			Expect(err).To(MatchError("unknown route name 'unknown'"))
+			_, err = router.URL("post", "id", "1")
+			Expect(err).To(MatchError("missing parameter 'post' for route 'post'"))
+			_, err = router.URL("post", "id", "1", "post", "")
+			Expect(err).To(MatchError("missing parameter 'post' for route 'post'"))
+			_, err = router.URL("post", "id", "1", "post", "2", "page", "3")
+			Expect(err).To(MatchError("unexpected parameter 'page' for route 'post'"))
+			_, err = router.URL("post", "id", "1", "post")
+			Expect(err).To(MatchError("missing value of parameter 'post' for route 'post'"))
+		})
+
+		It("should panic due to duplicate route names", func() {
+			router.GET("/users/:id", emptyHandler).Name("user")
+			Expect(func() {
+				router.GET("/accounts/:id", emptyHandler).Name("user")
+			}).To(Panic())
+
+			router.CollectErrors = true
+			router.Host("api.example.com").GET("/users/:id", emptyHandler).Name("user")
+			Expect(router.Err()).To(MatchError("duplicate route name 'user'"))
+		})
+
+		It("should build the URLs of named host routes", func() {
+			router.Host(":tenant.example.com").GET("/projects/:id", emptyHandler).Name("project")
+
+			Expect(router.URL("project", "id", "42")).To(Equal("/projects/42"))
+		})
+
+		It("should run the router middlewares for every request", func() {
+			calls := make([]string, 0)
+			router.Use(traceMiddleware(&calls, "first"))
+			router.Use(traceMiddleware(&calls, "second"), traceMiddleware(&calls, "third"))
+			router.GET("/users", func(ctx *fasthttp.RequestCtx) {
+				calls = append(calls, "handler")
+			})
+			router.NotFound = func(ctx *fasthttp.RequestCtx) {
+				calls = append(calls, "not found")
+			}
+
+			router.Handler(createRequestCtxFromPath("GET", "/users"))
+			Expect(calls).To(Equal([]string{"first", "second", "third", "handler"}))
+
+			calls = calls[:0]
+			router.Handler(createRequestCtxFromPath("GET", "/other"))
+			Expect(calls).To(Equal([]string{"first", "second", "third", "not found"}))
+
+			calls = calls[:0]
+			ctx := createRequestCtxFromPath("POST", "/users")
+			router.Handler(ctx)
+			Expect(calls).To(Equal([]string{"first", "second", "third"}))
+			Expect(ctx.Response.StatusCode()).To(Equal(fasthttp.StatusMethodNotAllowed))
+		})
+
+		It("should run the group middlewares for the routes added afterwards", func() {
+			calls := make([]string, 0)
+			group := router.Group("/api", traceMiddleware(&calls, "group"))
+			group.GET("/before", emptyHandler)
+			group.Use(traceMiddleware(&calls, "used"))
+			group.GET("/after", emptyHandler)
+
+			router.Handler(createRequestCtxFromPath("GET", "/api/before"))
+			Expect(calls).To(Equal([]string{"group"}))
+
+			calls = calls[:0]
+			router.Handler(createRequestCtxFromPath("GET", "/api/after"))
+			Expect(calls).To(Equal([]string{"group", "used"}))
+		})
+
+		It("should run the middlewares of the router, the groups and the subgroups in order", func() {
+			calls := make([]string, 0)
+			router.Use(traceMiddleware(&calls, "router"))
+			group := router.Group("/api", traceMiddleware(&calls, "group"))
+			subgroup := group.Group("/v1", traceMiddleware(&calls, "subgroup"))
+			group.Use(traceMiddleware(&calls, "group use"))
+			subgroup.Use(traceMiddleware(&calls, "subgroup use"))
+			subgroup.GET("/users", func(ctx *fasthttp.RequestCtx) {
+				calls = append(calls, "handler")
+			})
+
+			router.Handler(createRequestCtxFromPath("GET", "/api/v1/users"))
+			Expect(calls).To(Equal([]string{"router", "group", "group use", "subgroup", "subgroup use", "handler"}))
+		})
+
+		It("should not share the middlewares of sibling groups", func() {
+			calls := make([]string, 0)
+			middlewares := make([]Middleware, 1, 2)
+			middlewares[0] = traceMiddleware(&calls, "shared")
+			first := router.Group("/first", middlewares...)
+			second := router.Group("/second", middlewares...)
+			first.Use(traceMiddleware(&calls, "first"))
+			second.Use(traceMiddleware(&calls, "second"))
+			first.GET("/", emptyHandler)
+
+			router.Handler(createRequestCtxFromPath("GET", "/first/"))
+			Expect(calls).To(Equal([]string{"shared", "first"}))
+		})
+
+		It("should run the host middlewares for the routes added afterwards", func() {
+			calls := make([]string, 0)
+			router.Use(traceMiddleware(&calls, "router"))
+			host := router.Host("api.example.com")
+			host.Use(traceMiddleware(&calls, "host"))
+			host.GET("/users", emptyHandler)
+
+			ctx := createRequestCtxFromPath("GET", "/users")
+			ctx.Request.URI().SetHost("api.example.com")
+			router.Handler(ctx)
+			Expect(calls).To(Equal([]string{"router", "host"}))
+		})
+
+		It("should not allocate when resolving through middlewares", func() {
+			if raceEnabled {
+				Skip("the race detector makes sync.Pool allocate")
+			}
+			router.Use(func(next fasthttp.RequestHandler) fasthttp.RequestHandler {
+				return func(ctx *fasthttp.RequestCtx) {
+					next(ctx)
+				}
+			})
+			router.GET("/accounts/:account", emptyHandler)
+			router.UserValues = false
+
+			ctx := createRequestCtxFromPath("GET", "/accounts/account1")
+			Expect(testing.AllocsPerRun(100, func() {
+				router.Handler(ctx)
+			})).To(BeZero())
+		})
+
+		It("should run the route middlewares", func() {
+			calls := make([]string, 0)
+			router.GET("/users", func(ctx *fasthttp.RequestCtx) {
+				calls = append(calls, "handler")
+			}, traceMiddleware(&calls, "first"), traceMiddleware(&calls, "second"))
+			router.GET("/public", func(ctx *fasthttp.RequestCtx) {
+				calls = append(calls, "public")
+			})
+
+			router.Handler(createRequestCtxFromPath("GET", "/users"))
+			Expect(calls).To(Equal([]string{"first", "second", "handler"}))
+
+			calls = calls[:0]
+			router.Handler(createRequestCtxFromPath("GET", "/public"))
+			Expect(calls).To(Equal([]string{"public"}))
+		})
+
+		It("should run the route middlewares after the group ones", func() {
+			calls := make([]string, 0)
+			router.Use(traceMiddleware(&calls, "router"))
+			group := router.Group("/api", traceMiddleware(&calls, "group"))
+			subgroup := group.Group("/v1", traceMiddleware(&calls, "subgroup"))
+			subgroup.POST("/users", func(ctx *fasthttp.RequestCtx) {
+				calls = append(calls, "handler")
+			}, traceMiddleware(&calls, "route"))
+			subgroup.Any("/any", emptyHandler, traceMiddleware(&calls, "any"))
+			group.Handle("PROPFIND", "/users", emptyHandler, traceMiddleware(&calls, "handle"))
+
+			router.Handler(createRequestCtxFromPath("POST", "/api/v1/users"))
+			Expect(calls).To(Equal([]string{"router", "group", "subgroup", "route", "handler"}))
+
+			calls = calls[:0]
+			router.Handler(createRequestCtxFromPath("DELETE", "/api/v1/any"))
+			Expect(calls).To(Equal([]string{"router", "group", "subgroup", "any"}))
+
+			calls = calls[:0]
+			router.Handler(createRequestCtxFromPath("PROPFIND", "/api/users"))
+			Expect(calls).To(Equal([]string{"router", "group", "handle"}))
+		})
+
+		It("should run the route middlewares of host routes after the host ones", func() {
+			calls := make([]string, 0)
+			host := router.Host("api.example.com")
+			host.Use(traceMiddleware(&calls, "host"))
+			host.GET("/users", emptyHandler, traceMiddleware(&calls, "route"))
+			Expect(host.TryHandle("PUT", "/users", emptyHandler, traceMiddleware(&calls, "try"))).To(Succeed())
+
+			ctx := createRequestCtxFromPath("GET", "/users")
+			ctx.Request.URI().SetHost("api.example.com")
+			router.Handler(ctx)
+			Expect(calls).To(Equal([]string{"host", "route"}))
+
+			calls = calls[:0]
+			ctx = createRequestCtxFromPath("PUT", "/users")
+			ctx.Request.URI().SetHost("api.example.com")
+			router.Handler(ctx)
+			Expect(calls).To(Equal([]string{"host", "try"}))
+		})
+
+		It("should run the first middleware first", func() {
+			calls := make([]string, 0)
+			handler := Middlewares(func(ctx *fasthttp.RequestCtx) {
+				calls = append(calls, "handler")
+			}, traceMiddleware(&calls, "a"), traceMiddleware(&calls, "b"), traceMiddleware(&calls, "c"))
+
+			handler(createRequestCtxFromPath("GET", "/"))
+			Expect(calls).To(Equal([]string{"a", "b", "c", "handler"}))
+		})
+
+		It("should run the middlewares in the legacy order", func() {
+			calls := make([]string, 0)
+			router.LegacyMiddlewareOrder = true
+			router.Use(traceMiddleware(&calls, "use 1"), traceMiddleware(&calls, "use 2"))
+			group := router.Group("/api", traceMiddleware(&calls, "group 1"), traceMiddleware(&calls, "group 2"))
+			subgroup := group.Group("/v1", traceMiddleware(&calls, "subgroup"))
+			subgroup.GET("/users", func(ctx *fasthttp.RequestCtx) {
+				calls = append(calls, "handler")
+			}, traceMiddleware(&calls, "route 1"), traceMiddleware(&calls, "route 2"))
+			host := router.Host("api.example.com")
+			host.Use(traceMiddleware(&calls, "host 1"), traceMiddleware(&calls, "host 2"))
+			host.GET("/users", emptyHandler, traceMiddleware(&calls, "route 1"), traceMiddleware(&calls, "route 2"))
+
+			router.Handler(createRequestCtxFromPath("GET", "/api/v1/users"))
+			Expect(calls).To(Equal([]string{"use 2", "use 1", "group 2", "group 1", "subgroup", "route 2", "route 1", "handler"}))
+
+			calls = calls[:0]
+			ctx := createRequestCtxFromPath("GET", "/users")
+			ctx.Request.URI().SetHost("api.example.com")
+			router.Handler(ctx)
+			Expect(calls).To(Equal([]string{"use 2", "use 1", "host 2", "host 1", "route 2", "route 1"}))
+		})
+
+		It("should serve the requests under a mounted router", func() {
+			var path, prefix, id string
+			billing := New()
+			billing.GET("/invoices/:id", func(ctx *fasthttp.RequestCtx) {
+				path = string(ctx.Path())
+				prefix = ctx.UserValue(MountPrefixKey).(string)
+				id = string(GetParams(ctx).ByName("id"))
+			})
+			billing.GET("/", func(ctx *fasthttp.RequestCtx) {
+				path = string(ctx.Path())
+			})
+			router.Mount("/billing", billing)
+
+			ctx := createRequestCtxFromPath("GET", "/billing/invoices/42")
+			ctx.Request.URI().SetQueryString("page=2")
+			router.Handler(ctx)
+			Expect(path).To(Equal("/invoices/42"))
+			Expect(prefix).To(Equal("/billing"))
+			Expect(id).To(Equal("42"))
+			Expect(string(ctx.Path())).To(Equal("/billing/invoices/42"))
+			Expect(string(ctx.QueryArgs().Peek("page"))).To(Equal("2"))
+
+			router.Handler(createRequestCtxFromPath("GET", "/billing"))
+			Expect(path).To(Equal("/"))
+		})
+
+		It("should keep the not found and method not allowed handling of mounted routers", func() {
+			parentNotFound, notFound := false, false
+			router.NotFound = func(ctx *fasthttp.RequestCtx) {
+				parentNotFound = true
+			}
+			billing := New()
+			billing.NotFound = func(ctx *fasthttp.RequestCtx) {
+				notFound = true
+			}
+			billing.GET("/invoices", emptyHandler)
+			router.Mount("/billing/", billing)
+
+			router.Handler(createRequestCtxFromPath("GET", "/billing/other"))
+			Expect(notFound).To(BeTrue())
+			Expect(parentNotFound).To(BeFalse())
+
+			ctx := createRequestCtxFromPath("PROPFIND", "/billing/invoices")
+			router.Handler(ctx)
+			Expect(ctx.Response.StatusCode()).To(Equal(fasthttp.StatusMethodNotAllowed))
+			Expect(string(ctx.Response.Header.Peek("Allow"))).To(Equal("GET, HEAD"))
+
+			router.Handler(createRequestCtxFromPath("GET", "/billingother"))
+			Expect(parentNotFound).To(BeTrue())
+		})
+
+		It("should prefer the routes of the router and the longest mount prefix", func() {
+			var called string
+			router.GET("/api/status", func(ctx *fasthttp.RequestCtx) {
+				called = "status"
+			})
+			router.MountHandler("/api", func(ctx *fasthttp.RequestCtx) {
+				called = "api:" + string(ctx.Path())
+			})
+			router.MountHandler("/api/v2", func(ctx *fasthttp.RequestCtx) {
+				called = "v2:" + string(ctx.Path())
+			})
+
+			router.Handler(createRequestCtxFromPath("GET", "/api/status"))
+			Expect(called).To(Equal("status"))
+			router.Handler(createRequestCtxFromPath("DELETE", "/api/status"))
+			Expect(called).To(Equal("api:/status"))
+			router.Handler(createRequestCtxFromPath("GET", "/api/v2/users"))
+			Expect(called).To(Equal("v2:/users"))
+			router.Handler(createRequestCtxFromPath("GET", "/api/v21"))
+			Expect(called).To(Equal("api:/v21"))
+		})
+
+		It("should match the mount prefixes against the cleaned path", func() {
+			var path string
+			notFound := false
+			router.NotFound = func(ctx *fasthttp.RequestCtx) {
+				notFound = true
+			}
+			billing := New()
+			billing.GET("/invoices/:id", func(ctx *fasthttp.RequestCtx) {
+				path = string(ctx.Path())
+			})
+			router.Mount("/billing", billing)
+
+			for _, original := range []string{"/x/../billing/invoices/1", "//billing/invoices/1"} {
+				path = ""
+				ctx := createRequestCtxFromPath("GET", original)
+				router.Handler(ctx)
+				Expect(path).To(Equal("/invoices/1"), original)
+				Expect(string(ctx.Request.URI().PathOriginal())).To(Equal(original))
+			}
+
+			ctx := createRequestCtxFromPath("GET", "/billing/invoices/a%2525")
+			router.Handler(ctx)
+			Expect(path).To(Equal("/invoices/a%25"))
+
+			path = ""
+			router.Handler(createRequestCtxFromPath("GET", "/billing/../admin"))
+			Expect(path).To(BeEmpty())
+			Expect(notFound).To(BeTrue())
+		})
+
+		It("should run the router middlewares before the mounted handlers", func() {
+			calls := make([]string, 0)
+			router.Use(traceMiddleware(&calls, "router"))
+			billing := New()
+			billing.Use(traceMiddleware(&calls, "billing"))
+			billing.GET("/", func(ctx *fasthttp.RequestCtx) {
+				calls = append(calls, "handler")
+			})
+			router.Mount("/billing", billing)
+
+			router.Handler(createRequestCtxFromPath("GET", "/billing/"))
+			Expect(calls).To(Equal([]string{"router", "billing", "handler"}))
+		})
+
+		It("should panic due to invalid mount prefixes", func() {
+			router.MountHandler("/billing", emptyHandler)
+			Expect(func() {
+				router.MountHandler("/billing/", emptyHandler)
+			}).To(Panic())
+			Expect(func() {
+				router.MountHandler("billing", emptyHandler)
+			}).To(Panic())
+			Expect(func() {
+				router.MountHandler("/tenants/:id", emptyHandler)
+			}).To(Panic())
+		})
+
+		It("should not allocate when serving mounted routers", func() {
+			if raceEnabled {
+				Skip("the race detector makes sync.Pool allocate")
+			}
+			billing := New()
+			billing.UserValues = false
+			billing.GET("/invoices/:id", emptyHandler)
+			router.Mount("/billing", billing)
+
+			ctx := createRequestCtxFromPath("GET", "/billing/invoices/42")
+			Expect(testing.AllocsPerRun(100, func() {
+				router.Handler(ctx)
+			})).To(BeZero())
+		})
+	})
+})
+
+func BenchmarkSplit(b *testing.B) {
+	path := []byte("/path/with/four/parts")
+	tokens := make([][]byte, 0)
+
+	for i := 0; i < b.N; i++ {
+		tokens = Split(path, tokens)
+		tokens = tokens[0:0]
+	}
+}
+
+func BenchmarkRouter_Handler(b *testing.B) {
+	router := New()
+	router.GET("/", emptyHandler)
+	ctx := fasthttp.RequestCtx{}
+	ctx.Request.SetRequestURI("/")
+
+	for i := 0; i < b.N; i++ {
+		router.Handler(&ctx)
+	}
+}
+
+func BenchmarkRouter_HandlerStatic(b *testing.B) {
+	router := New()
+	router.UserValues = false
+	router.GET("/static/path/with/segments", emptyHandler)
+	ctx := fasthttp.RequestCtx{}
+	ctx.Request.SetRequestURI("/static/path/with/segments")
+
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		router.Handler(&ctx)
+	}
+}
+
+func BenchmarkRouter_HandlerParams(b *testing.B) {
+	router := New()
+	router.UserValues = false
+	router.GET("/accounts/:account/transactions/:id", emptyHandler)
+	ctx := fasthttp.RequestCtx{}
+	ctx.Request.SetRequestURI("/accounts/account1/transactions/10")
+
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		router.Handler(&ctx)
+	}
+}
+
+func BenchmarkRouter_HandlerUserValues(b *testing.B) {
+	router := New()
+	router.GET("/accounts/:account/transactions/:id", emptyHandler)
+	ctx := fasthttp.RequestCtx{}
+	ctx.Request.SetRequestURI("/accounts/account1/transactions/10")
+
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		router.Handler(&ctx)
+	}
+}
+
+func BenchmarkRouter_HandlerCatchAll(b *testing.B) {
+	router := New()
+	router.UserValues = false
+	router.GET("/files/*filepath", emptyHandler)
+	ctx := fasthttp.RequestCtx{}
+	ctx.Request.SetRequestURI("/files/css/site/main.css")
+
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		router.Handler(&ctx)
+	}
+}
+
+func BenchmarkRouter_HandlerMethods(b *testing.B) {
+	methods := []string{"GET", "HEAD", "POST", "PUT", "PATCH", "DELETE", "OPTIONS", "CONNECT", "TRACE", "PROPFIND"}
+	router := New()
+	router.UserValues = false
+	for _, method := range methods {
+		router.Handle(method, "/accounts/:account", emptyHandler)
+	}
+
+	for _, method := range methods {
+		b.Run(method, func(b *testing.B) {
+			ctx := fasthttp.RequestCtx{}
+			ctx.Request.Header.SetMethod(method)
+			ctx.Request.SetRequestURI("/accounts/account1")
+
+			b.ReportAllocs()
+			for i := 0; i < b.N; i++ {
+				router.Handler(&ctx)
+			}
+		})
 	}
 }
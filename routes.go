@@ -0,0 +1,122 @@
+package fasthttp_router
+
+import (
+	"github.com/valyala/fasthttp"
+	"reflect"
+	"runtime"
+	"sort"
+)
+
+// RouteInfo describes a registered route.
+type RouteInfo struct {
+	// Host is the host pattern of the route, empty for the routes of the
+	// router itself.
+	Host string
+
+	// Method is the method of the route, empty for the handlers mounted
+	// by MountHandler.
+	Method string
+
+	// Pattern is the pattern the route was registered with, group prefix
+	// included.
+	Pattern string
+
+	// Path is the variant of the pattern the route matches. It differs
+	// from Pattern for each variant of a pattern with optional segments.
+	Path string
+
+	// Params are the names of the parameters of the route, in order.
+	Params []string
+
+	// Handler is the name of the handler function, as reported by the
+	// runtime, before it was wrapped by middlewares.
+	Handler string
+}
+
+// Routes returns the registered routes, as visited by Walk.
+func (router *Router) Routes() []RouteInfo {
+	routes := make([]RouteInfo, 0)
+	router.Walk(func(route RouteInfo) error {
+		routes = append(routes, route)
+		return nil
+	})
+	return routes
+}
+
+// Walk calls `fn` for every registered route, stopping at the first error,
+// which it returns. Methods are visited in alphabetical order and, for each
+// of them, routes in matching priority order. Host routes are visited
+// after the routes of the router itself, in the order their hosts were
+// added, and mounted routes last, in the order they were mounted.
+//
+// The routes of a mounted router are visited with the prefix added to their
+// Pattern and Path. A handler mounted by MountHandler is visited as a single
+// route with an empty Method, as it serves any, and the prefix as Pattern
+// and Path.
+func (router *Router) Walk(fn func(RouteInfo) error) error {
+	if err := router.walk("", fn); err != nil {
+		return err
+	}
+	for _, host := range router.hosts {
+		if err := host.routes.walk(host.pattern, fn); err != nil {
+			return err
+		}
+	}
+	for _, m := range router.mounts {
+		if err := m.walk(fn); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (m *mount) walk(fn func(RouteInfo) error) error {
+	if m.routes == nil {
+		return fn(RouteInfo{
+			Pattern: m.path,
+			Path:    m.path,
+			Handler: handlerName(m.handler),
+		})
+	}
+	return m.routes.Walk(func(route RouteInfo) error {
+		route.Pattern = m.path + route.Pattern
+		route.Path = m.path + route.Path
+		return fn(route)
+	})
+}
+
+func (router *Router) walk(host string, fn func(RouteInfo) error) error {
+	methods := make([]string, 0, methodCount+len(router.custom))
+	for i, root := range router.trees {
+		if root != nil {
+			methods = append(methods, methodNames[i])
+		}
+	}
+	for method := range router.custom {
+		methods = append(methods, method)
+	}
+	sort.Strings(methods)
+	for _, method := range methods {
+		err := router.tree(method).Walk(func(n *node) error {
+			return fn(RouteInfo{
+				Host:    host,
+				Method:  method,
+				Pattern: n.route,
+				Path:    n.path,
+				Params:  append([]string(nil), n.names...),
+				Handler: n.handlerName,
+			})
+		})
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func handlerName(handler fasthttp.RequestHandler) string {
+	if f := runtime.FuncForPC(reflect.ValueOf(handler).Pointer()); f != nil {
+		return f.Name()
+	}
+	return ""
+}
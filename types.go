@@ -0,0 +1,60 @@
+package fasthttp_router
+
+// defaultParamTypes returns the built-in types of `:param<type>` tokens.
+func defaultParamTypes() map[string]func([]byte) bool {
+	return map[string]func([]byte) bool{
+		"int":   isInt,
+		"uuid":  isUUID,
+		"alpha": isAlpha,
+	}
+}
+
+// isInt accepts decimal integers, optionally negative.
+func isInt(value []byte) bool {
+	if len(value) > 0 && value[0] == '-' {
+		value = value[1:]
+	}
+	if len(value) == 0 {
+		return false
+	}
+	for _, c := range value {
+		if c < '0' || c > '9' {
+			return false
+		}
+	}
+	return true
+}
+
+// isUUID accepts UUIDs in their canonical 8-4-4-4-12 hexadecimal form, in
+// any case.
+func isUUID(value []byte) bool {
+	if len(value) != 36 {
+		return false
+	}
+	for i, c := range value {
+		switch i {
+		case 8, 13, 18, 23:
+			if c != '-' {
+				return false
+			}
+		default:
+			if !isHex(c) {
+				return false
+			}
+		}
+	}
+	return true
+}
+
+// isAlpha accepts ASCII letters.
+func isAlpha(value []byte) bool {
+	if len(value) == 0 {
+		return false
+	}
+	for _, c := range value {
+		if (c < 'a' || c > 'z') && (c < 'A' || c > 'Z') {
+			return false
+		}
+	}
+	return true
+}